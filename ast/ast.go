@@ -36,6 +36,13 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern defines the target of a binding, which can destructure
+// the value bound to it
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 // Declaration defines a top level declaration (e.g. class, fn)
 type Declaration interface {
 	Node
@@ -46,6 +53,9 @@ type Declaration interface {
 type Program struct {
 	Statements []Statement
 	Functions  []*FunctionDecl
+	Classes    []*ClassDecl
+	Traits     []*TraitDecl
+	Impls      []*ImplDecl
 }
 
 // TokenLiteral implements Node for string
@@ -67,14 +77,45 @@ func (p *Program) String() string {
 
 // Context implements Node for Program
 func (p *Program) Context() lexer.Context {
-	return lexer.Context{}
+	nodes := []Node{}
+	for _, s := range p.Statements {
+		nodes = append(nodes, s)
+	}
+	for _, f := range p.Functions {
+		nodes = append(nodes, f)
+	}
+	for _, c := range p.Classes {
+		nodes = append(nodes, c)
+	}
+	for _, t := range p.Traits {
+		nodes = append(nodes, t)
+	}
+	for _, i := range p.Impls {
+		nodes = append(nodes, i)
+	}
+	if len(nodes) == 0 {
+		return lexer.Context{}
+	}
+
+	// declarations are stored separately, so the nodes are not in source order
+	first, last := nodes[0].Context(), nodes[0].Context()
+	for _, n := range nodes[1:] {
+		con := n.Context()
+		if con.Offset < first.Offset {
+			first = con
+		}
+		if con.End.Offset > last.End.Offset {
+			last = con
+		}
+	}
+	return lexer.Span(first, last)
 }
 
 // FunctionDecl represents a function declaration
 type FunctionDecl struct {
 	Token  lexer.Token
 	Name   *Identifier
-	Params []*Identifier
+	Params []Pattern
 	Body   *BlockStatement
 }
 
@@ -95,7 +136,7 @@ func (fd *FunctionDecl) String() string {
 		params = append(params, p.String())
 	}
 	out.WriteString("fn ")
-	out.WriteString(fd.String())
+	out.WriteString(fd.Name.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
@@ -106,5 +147,168 @@ func (fd *FunctionDecl) String() string {
 
 // Context implements Node for FunctionDecl
 func (fd *FunctionDecl) Context() lexer.Context {
-	return fd.Token.Pos
+	if fd.Body == nil {
+		return fd.Token.Pos
+	}
+	return span(fd.Token.Pos, fd.Body)
+}
+
+// ClassDecl represents a class declaration
+type ClassDecl struct {
+	Token lexer.Token
+	Name  *Identifier
+	// Fields are declared with let, and their values are the defaults
+	// given to each new instance
+	Fields []*LetStatement
+	// Methods take the instance they are called on as their first parameter
+	Methods []*FunctionDecl
+	Close   lexer.Token
+}
+
+func (cd *ClassDecl) declarationNode() {}
+
+// TokenLiteral implements Node for ClassDecl
+func (cd *ClassDecl) TokenLiteral() string {
+	return cd.Token.Literal
+}
+
+// String implements Node for ClassDecl
+func (cd *ClassDecl) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("class ")
+	out.WriteString(cd.Name.String())
+	out.WriteString(" {\n")
+	for _, field := range cd.Fields {
+		out.WriteString(field.String() + "\n")
+	}
+	for _, method := range cd.Methods {
+		out.WriteString(method.String() + "\n")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Context implements Node for ClassDecl
+func (cd *ClassDecl) Context() lexer.Context {
+	return closeSpan(cd.Token.Pos, cd.Close)
+}
+
+// FunctionSig represents the signature of a method that a trait requires
+type FunctionSig struct {
+	Token  lexer.Token
+	Name   *Identifier
+	Params []Pattern
+	Close  lexer.Token
+}
+
+// TokenLiteral implements Node for FunctionSig
+func (fs *FunctionSig) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+// String implements Node for FunctionSig
+func (fs *FunctionSig) String() string {
+	params := []string{}
+	for _, p := range fs.Params {
+		params = append(params, p.String())
+	}
+	return "fn " + fs.Name.String() + "(" + strings.Join(params, ", ") + ");"
+}
+
+// Context implements Node for FunctionSig
+func (fs *FunctionSig) Context() lexer.Context {
+	return closeSpan(fs.Token.Pos, fs.Close)
+}
+
+// TraitDecl represents a trait declaration
+type TraitDecl struct {
+	Token   lexer.Token
+	Name    *Identifier
+	Methods []*FunctionSig
+	Close   lexer.Token
+}
+
+func (td *TraitDecl) declarationNode() {}
+
+// TokenLiteral implements Node for TraitDecl
+func (td *TraitDecl) TokenLiteral() string {
+	return td.Token.Literal
+}
+
+// String implements Node for TraitDecl
+func (td *TraitDecl) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("trait ")
+	out.WriteString(td.Name.String())
+	out.WriteString(" {\n")
+	for _, method := range td.Methods {
+		out.WriteString(method.String() + "\n")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Context implements Node for TraitDecl
+func (td *TraitDecl) Context() lexer.Context {
+	return closeSpan(td.Token.Pos, td.Close)
+}
+
+// ImplDecl represents the implementation of a trait for a class
+type ImplDecl struct {
+	Token   lexer.Token
+	Trait   *Identifier
+	Class   *Identifier
+	Methods []*FunctionDecl
+	Close   lexer.Token
+}
+
+func (id *ImplDecl) declarationNode() {}
+
+// TokenLiteral implements Node for ImplDecl
+func (id *ImplDecl) TokenLiteral() string {
+	return id.Token.Literal
+}
+
+// String implements Node for ImplDecl
+func (id *ImplDecl) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("impl ")
+	out.WriteString(id.Trait.String())
+	out.WriteString(" for ")
+	out.WriteString(id.Class.String())
+	out.WriteString(" {\n")
+	for _, method := range id.Methods {
+		out.WriteString(method.String() + "\n")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Context implements Node for ImplDecl
+func (id *ImplDecl) Context() lexer.Context {
+	return closeSpan(id.Token.Pos, id.Close)
+}
+
+// span returns a range from the start of start to the end of end.
+// If end is missing, start is returned as is.
+func span(start lexer.Context, end Node) lexer.Context {
+	if end == nil {
+		return start
+	}
+	return lexer.Span(start, end.Context())
+}
+
+// closeSpan returns a range from the start of start to the end of a
+// closing delimiter, if the parser recorded one
+func closeSpan(start lexer.Context, close lexer.Token) lexer.Context {
+	if close.Type == "" {
+		return start
+	}
+	return lexer.Span(start, close.Pos)
 }
@@ -36,3 +36,20 @@ func TestString(t *testing.T) {
 		t.Fatalf("Incorrect p.String(): got %q", progstring)
 	}
 }
+
+func TestFunctionDeclString(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{Token: lexer.Token{Type: lexer.IDENT, Literal: name}, Value: name}
+	}
+	decl := &FunctionDecl{
+		Token:  lexer.Token{Type: lexer.FUNCTION, Literal: lexer.FUNCTION},
+		Name:   ident("add"),
+		Params: []Pattern{ident("a"), ident("b")},
+		Body: &BlockStatement{
+			Statements: []Statement{&ExprStatement{Expression: ident("a")}},
+		},
+	}
+	if str := decl.String(); str != "fn add(a, b){\na\n\n}" {
+		t.Fatalf("Incorrect decl.String(): got %q", str)
+	}
+}
@@ -18,6 +18,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 
 // TokenLiteral - implements Node for Identifier
 func (i *Identifier) TokenLiteral() string {
@@ -64,7 +65,7 @@ func (pe *PrefixExpr) String() string {
 
 // Context implements Node for PrefixExpr
 func (pe *PrefixExpr) Context() lexer.Context {
-	return pe.Token.Pos
+	return span(pe.Token.Pos, pe.Right)
 }
 
 //*----------| InfixExpr |----------*/
@@ -97,7 +98,46 @@ func (ie *InfixExpr) String() string {
 
 // Context implements Node for InfixExpr
 func (ie *InfixExpr) Context() lexer.Context {
-	return ie.Token.Pos
+	if ie.Left == nil {
+		return span(ie.Token.Pos, ie.Right)
+	}
+	return span(ie.Left.Context(), ie.Right)
+}
+
+//*----------| AssignExpr |----------*/
+
+// AssignExpr represents an assignment to a variable or an index,
+// such as x = 1, or a compound assignment such as arr[0] += 2
+type AssignExpr struct {
+	Token  lexer.Token
+	Target Expression
+	// Operator is the operator applied by a compound assignment,
+	// e.g. + for +=, and is empty for a plain assignment
+	Operator string
+	Value    Expression
+}
+
+func (ae *AssignExpr) expressionNode() {}
+
+// TokenLiteral implements Node for AssignExpr
+func (ae *AssignExpr) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+// String implements Node for AssignExpr
+func (ae *AssignExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(" + ae.Target.String())
+	out.WriteString(" " + ae.Operator + "= ")
+	out.WriteString(ae.Value.String() + ")")
+
+	return out.String()
+}
+
+// Context implements Node for AssignExpr
+func (ae *AssignExpr) Context() lexer.Context {
+	return span(ae.Target.Context(), ae.Value)
 }
 
 //*----------| IfExpression |----------*/
@@ -137,7 +177,67 @@ func (ie *IfExpression) String() string {
 
 // Context implements Node for IfExpression
 func (ie *IfExpression) Context() lexer.Context {
-	return ie.Token.Pos
+	if ie.Alternative != nil {
+		return span(ie.Token.Pos, ie.Alternative)
+	}
+	if ie.Result != nil {
+		return span(ie.Token.Pos, ie.Result)
+	}
+	return span(ie.Token.Pos, ie.Condition)
+}
+
+//*----------| MatchExpr |----------*/
+
+// MatchExpr represents a match expression, which evaluates the
+// body of the first arm whose pattern matches its subject
+type MatchExpr struct {
+	Token   lexer.Token
+	Subject Expression
+	Arms    []*MatchArm
+	// Close is the closing brace of the arms
+	Close lexer.Token
+}
+
+// MatchArm is a single arm of a match expression
+type MatchArm struct {
+	Pattern Pattern
+	// Guard is the condition after if, or nil if there is none
+	Guard Expression
+	// Body is a block, or an expression wrapped in a block
+	Body *BlockStatement
+}
+
+func (me *MatchExpr) expressionNode() {}
+
+// TokenLiteral implements Node for MatchExpr
+func (me *MatchExpr) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+// String implements Node for MatchExpr
+func (me *MatchExpr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match ")
+	out.WriteString(me.Subject.String())
+	out.WriteString(" {\n")
+	for _, arm := range me.Arms {
+		out.WriteString(arm.Pattern.String())
+		if arm.Guard != nil {
+			out.WriteString(" if " + arm.Guard.String())
+		}
+		out.WriteString(" => ")
+		out.WriteString(arm.Body.String())
+		out.WriteString(",\n")
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Context implements Node for MatchExpr
+func (me *MatchExpr) Context() lexer.Context {
+	return closeSpan(me.Token.Pos, me.Close)
 }
 
 //*----------| Fnliteral |----------*/
@@ -145,7 +245,7 @@ func (ie *IfExpression) Context() lexer.Context {
 // FnLiteral represents a function declaration in Monkey
 type FnLiteral struct {
 	Token  lexer.Token
-	Params []*Identifier
+	Params []Pattern
 	Body   *BlockStatement
 }
 
@@ -175,16 +275,35 @@ func (fl *FnLiteral) String() string {
 
 // Context implements Node for FnLiteral
 func (fl *FnLiteral) Context() lexer.Context {
-	return fl.Token.Pos
+	if fl.Body == nil {
+		return fl.Token.Pos
+	}
+	return span(fl.Token.Pos, fl.Body)
 }
 
 //*----------| FunctionCall |----------*/
 
 // FunctionCall defines a function call in Monkey
 type FunctionCall struct {
-	Token  lexer.Token
-	Ident  Expression
+	Token lexer.Token
+	Ident Expression
+	// Params are the positional arguments, any of which may be a Spread
 	Params []Expression
+	// Keywords are the keyword arguments, which follow the positional ones
+	Keywords []*KeywordArg
+	// Close is the closing parenthesis of the call
+	Close lexer.Token
+}
+
+// KeywordArg is an argument passed to a parameter by name, as in f(b: 2)
+type KeywordArg struct {
+	Name  *Identifier
+	Value Expression
+}
+
+// String returns the keyword argument as it is written
+func (ka *KeywordArg) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
 }
 
 func (fc *FunctionCall) expressionNode() {}
@@ -203,6 +322,9 @@ func (fc *FunctionCall) String() string {
 	for _, p := range fc.Params {
 		params = append(params, p.String())
 	}
+	for _, kw := range fc.Keywords {
+		params = append(params, kw.String())
+	}
 	out.WriteString(fc.Ident.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
@@ -213,7 +335,33 @@ func (fc *FunctionCall) String() string {
 
 // Context implements Node for FunctionCall
 func (fc *FunctionCall) Context() lexer.Context {
-	return fc.Token.Pos
+	return closeSpan(fc.Ident.Context(), fc.Close)
+}
+
+//*----------| Spread |----------*/
+
+// Spread passes the elements of an array or tuple
+// as separate arguments to a function, as in f(...xs)
+type Spread struct {
+	Token lexer.Token
+	Value Expression
+}
+
+func (sp *Spread) expressionNode() {}
+
+// TokenLiteral implements Node for Spread
+func (sp *Spread) TokenLiteral() string {
+	return sp.Token.Literal
+}
+
+// String implements Node for Spread
+func (sp *Spread) String() string {
+	return "..." + sp.Value.String()
+}
+
+// Context implements Node for Spread
+func (sp *Spread) Context() lexer.Context {
+	return span(sp.Token.Pos, sp.Value)
 }
 
 //*----------| DotExpression |----------*/
@@ -247,7 +395,7 @@ func (de *DotExpression) String() string {
 
 // Context implements Node for DotExpression
 func (de *DotExpression) Context() lexer.Context {
-	return de.Token.Pos
+	return span(de.Left.Context(), de.Right)
 }
 
 //*----------| Literals |----------*/
@@ -350,10 +498,55 @@ func (s *Str) Context() lexer.Context {
 }
 
 func strFromLit(raw string) Literal {
-	// todo: add method to parse escaped chars
 	return &Str{Inner: raw}
 }
 
+//! InterpStr
+
+// InterpStr represents a string literal with interpolated expressions
+type InterpStr struct {
+	Token lexer.Token
+	// Parts holds the text of the string as *Str nodes, in order with the
+	// interpolated expressions. Empty runs of text are left out.
+	Parts []Expression
+	// Close is the STRTAIL token that ends the string
+	Close lexer.Token
+}
+
+// Literal implements Literal for InterpStr
+func (is *InterpStr) Literal()        {}
+func (is *InterpStr) expressionNode() {}
+
+// TokenLiteral implements Node for InterpStr
+func (is *InterpStr) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+// String implements Node for InterpStr
+func (is *InterpStr) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		// text comes from the string's own tokens, anything else was interpolated
+		if str, ok := part.(*Str); ok && str.Token.Type != lexer.STRLIT {
+			out.WriteString(str.Inner)
+			continue
+		}
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString(`"`)
+
+	return out.String()
+}
+
+// Context implements Node for InterpStr
+func (is *InterpStr) Context() lexer.Context {
+	return closeSpan(is.Token.Pos, is.Close)
+}
+
 //! Bool
 
 // Bool represents a boolean literal in the Monkey AST
@@ -385,6 +578,8 @@ func (b *Bool) Context() lexer.Context {
 type Array struct {
 	Token    lexer.Token
 	Elements []Expression
+	// Close is the closing bracket of the literal
+	Close lexer.Token
 }
 
 // Literal implements Literal for Array
@@ -416,13 +611,57 @@ func (a *Array) String() string {
 
 // Context implements Node for Array
 func (a *Array) Context() lexer.Context {
-	return a.Token.Pos
+	return closeSpan(a.Token.Pos, a.Close)
+}
+
+// Tuple represents a tuple literal
+type Tuple struct {
+	Token    lexer.Token
+	Elements []Expression
+	// Close is the closing paren of the literal
+	Close lexer.Token
+}
+
+// Literal implements Literal for Tuple
+func (t *Tuple) Literal()        {}
+func (t *Tuple) expressionNode() {}
+
+// TokenLiteral implements Node for Tuple
+func (t *Tuple) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+// String implements Node for Tuple
+func (t *Tuple) String() string {
+	elems := []string{}
+	for _, elem := range t.Elements {
+		elems = append(elems, elem.String())
+	}
+	return tupleString(elems)
+}
+
+// Context implements Node for Tuple
+func (t *Tuple) Context() lexer.Context {
+	return closeSpan(t.Token.Pos, t.Close)
+}
+
+// tupleString formats the elements of a tuple, with a trailing
+// comma if there is only one so that it is not a grouped expression
+func tupleString(elems []string) string {
+	if len(elems) == 1 {
+		return "(" + elems[0] + ",)"
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
 }
 
 // Map represents a map literal
 type Map struct {
 	Token    lexer.Token
 	Elements map[Expression]Expression
+	// Keys holds the keys of Elements in source order
+	Keys []Expression
+	// Close is the closing brace of the literal
+	Close lexer.Token
 }
 
 // Literal implements Literal for Map
@@ -440,8 +679,8 @@ func (m *Map) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("{\n")
-	for idx, val := range m.Elements {
-		out.WriteString(fmt.Sprintf("%s : %s,\n", idx.String(), val.String()))
+	for _, idx := range m.Keys {
+		out.WriteString(fmt.Sprintf("%s : %s,\n", idx.String(), m.Elements[idx].String()))
 	}
 	out.WriteString("}")
 
@@ -450,7 +689,105 @@ func (m *Map) String() string {
 
 // Context implements Node for Map
 func (m *Map) Context() lexer.Context {
-	return m.Token.Pos
+	return closeSpan(m.Token.Pos, m.Close)
+}
+
+// Set represents a set literal, such as {1, 2, 3}
+type Set struct {
+	Token    lexer.Token
+	Elements []Expression
+	// Close is the closing brace of the literal
+	Close lexer.Token
+}
+
+// Literal implements Literal for Set
+func (s *Set) Literal()        {}
+func (s *Set) expressionNode() {}
+
+// TokenLiteral implements Node for Set
+func (s *Set) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+// String implements Node for Set
+func (s *Set) String() string {
+	elems := []string{}
+	for _, elem := range s.Elements {
+		elems = append(elems, elem.String())
+	}
+	return "{" + strings.Join(elems, ", ") + "}"
+}
+
+// Context implements Node for Set
+func (s *Set) Context() lexer.Context {
+	return closeSpan(s.Token.Pos, s.Close)
+}
+
+// RangeExpr represents a range of integers, either as a range
+// literal such as [1..10] or as the index of a slice such as xs[1..]
+type RangeExpr struct {
+	// Token is the opening bracket of a range literal,
+	// or the range operator of a slice
+	Token lexer.Token
+	// Start, End and Step are nil if they were left out
+	Start Expression
+	End   Expression
+	Step  Expression
+	// Inclusive is whether the range includes its end, as with ..=
+	Inclusive bool
+	// Close is the closing bracket of a range literal
+	Close lexer.Token
+}
+
+func (re *RangeExpr) expressionNode() {}
+
+// TokenLiteral implements Node for RangeExpr
+func (re *RangeExpr) TokenLiteral() string {
+	return re.Token.Literal
+}
+
+// String implements Node for RangeExpr
+func (re *RangeExpr) String() string {
+	var out bytes.Buffer
+
+	if re.Start != nil {
+		out.WriteString(re.Start.String())
+	}
+	if re.Inclusive {
+		out.WriteString("..=")
+	} else {
+		out.WriteString("..")
+	}
+	if re.End != nil {
+		out.WriteString(re.End.String())
+	}
+	if re.Step != nil {
+		out.WriteString(":" + re.Step.String())
+	}
+
+	if re.Token.Type == lexer.LSBRKT {
+		return "[" + out.String() + "]"
+	}
+	return out.String()
+}
+
+// Context implements Node for RangeExpr
+func (re *RangeExpr) Context() lexer.Context {
+	if re.Token.Type == lexer.LSBRKT {
+		return closeSpan(re.Token.Pos, re.Close)
+	}
+	start := re.Token.Pos
+	if re.Start != nil {
+		start = re.Start.Context()
+	}
+	switch {
+	case re.Step != nil:
+		return span(start, re.Step)
+	case re.End != nil:
+		return span(start, re.End)
+	default:
+		return lexer.Span(start, re.Token.Pos)
+	}
 }
 
 // IndexExpr represents an index into an array or a map
@@ -458,6 +795,8 @@ type IndexExpr struct {
 	Token lexer.Token
 	Left  Expression
 	Index Expression
+	// Close is the closing bracket of the index
+	Close lexer.Token
 }
 
 func (ie *IndexExpr) expressionNode() {}
@@ -474,5 +813,5 @@ func (ie *IndexExpr) String() string {
 
 // Context implements Node for IndexExpr
 func (ie *IndexExpr) Context() lexer.Context {
-	return ie.Token.Pos
+	return closeSpan(ie.Left.Context(), ie.Close)
 }
@@ -0,0 +1,267 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/cartoon-raccoon/lemur/lexer"
+)
+
+//*----------| TuplePattern |----------*/
+
+// TuplePattern destructures a tuple, binding each of its elements
+// to the pattern in the same position
+type TuplePattern struct {
+	Token    lexer.Token
+	Elements []Pattern
+	Close    lexer.Token
+}
+
+func (tp *TuplePattern) patternNode() {}
+
+// TokenLiteral implements Node for TuplePattern
+func (tp *TuplePattern) TokenLiteral() string {
+	return tp.Token.Literal
+}
+
+// String implements Node for TuplePattern
+func (tp *TuplePattern) String() string {
+	elems := []string{}
+	for _, elem := range tp.Elements {
+		elems = append(elems, elem.String())
+	}
+	return tupleString(elems)
+}
+
+// Context implements Node for TuplePattern
+func (tp *TuplePattern) Context() lexer.Context {
+	return closeSpan(tp.Token.Pos, tp.Close)
+}
+
+//*----------| ArrayPattern |----------*/
+
+// ArrayPattern destructures an array, binding each of its elements
+// to the pattern in the same position. One of the patterns may be
+// a RestPattern, which takes all the elements not matched by the others.
+type ArrayPattern struct {
+	Token    lexer.Token
+	Elements []Pattern
+	Close    lexer.Token
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+// TokenLiteral implements Node for ArrayPattern
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+// String implements Node for ArrayPattern
+func (ap *ArrayPattern) String() string {
+	elems := []string{}
+	for _, elem := range ap.Elements {
+		elems = append(elems, elem.String())
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+// Context implements Node for ArrayPattern
+func (ap *ArrayPattern) Context() lexer.Context {
+	return closeSpan(ap.Token.Pos, ap.Close)
+}
+
+//*----------| MapPattern |----------*/
+
+// MapPattern destructures a map with string keys, or the fields of an
+// instance. In {name, age: a}, the value of "name" is bound to name,
+// and the value of "age" is bound to the pattern a.
+type MapPattern struct {
+	Token lexer.Token
+	Keys  []*Identifier
+	// Values holds the pattern for each key. For a key given on its
+	// own, the pattern is the key itself.
+	Values []Pattern
+	Close  lexer.Token
+}
+
+func (mp *MapPattern) patternNode() {}
+
+// TokenLiteral implements Node for MapPattern
+func (mp *MapPattern) TokenLiteral() string {
+	return mp.Token.Literal
+}
+
+// String implements Node for MapPattern
+func (mp *MapPattern) String() string {
+	entries := []string{}
+	for i, key := range mp.Keys {
+		if mp.Values[i] == Pattern(key) {
+			entries = append(entries, key.String())
+		} else {
+			entries = append(entries, key.String()+": "+mp.Values[i].String())
+		}
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// Context implements Node for MapPattern
+func (mp *MapPattern) Context() lexer.Context {
+	return closeSpan(mp.Token.Pos, mp.Close)
+}
+
+//*----------| RestPattern |----------*/
+
+// RestPattern binds the elements of an array or tuple that are
+// not matched by the other patterns around it, as in [head, ...tail]
+type RestPattern struct {
+	Token lexer.Token
+	Name  *Identifier
+}
+
+func (rp *RestPattern) patternNode() {}
+
+// TokenLiteral implements Node for RestPattern
+func (rp *RestPattern) TokenLiteral() string {
+	return rp.Token.Literal
+}
+
+// String implements Node for RestPattern
+func (rp *RestPattern) String() string {
+	return "..." + rp.Name.String()
+}
+
+// Context implements Node for RestPattern
+func (rp *RestPattern) Context() lexer.Context {
+	return span(rp.Token.Pos, rp.Name)
+}
+
+//*----------| WildcardPattern |----------*/
+
+// WildcardPattern is written as _, and matches any value without binding it
+type WildcardPattern struct {
+	Token lexer.Token
+}
+
+func (wp *WildcardPattern) patternNode() {}
+
+// TokenLiteral implements Node for WildcardPattern
+func (wp *WildcardPattern) TokenLiteral() string {
+	return wp.Token.Literal
+}
+
+// String implements Node for WildcardPattern
+func (wp *WildcardPattern) String() string {
+	return "_"
+}
+
+// Context implements Node for WildcardPattern
+func (wp *WildcardPattern) Context() lexer.Context {
+	return wp.Token.Pos
+}
+
+//*----------| LiteralPattern |----------*/
+
+// LiteralPattern matches values equal to a literal number, string or boolean
+type LiteralPattern struct {
+	// Value is an Int, Flt, Str or Bool, or a negated Int or Flt
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+// TokenLiteral implements Node for LiteralPattern
+func (lp *LiteralPattern) TokenLiteral() string {
+	return lp.Value.TokenLiteral()
+}
+
+// String implements Node for LiteralPattern
+func (lp *LiteralPattern) String() string {
+	return lp.Value.String()
+}
+
+// Context implements Node for LiteralPattern
+func (lp *LiteralPattern) Context() lexer.Context {
+	return lp.Value.Context()
+}
+
+//*----------| RangePattern |----------*/
+
+// RangePattern matches integers in a range, such as 1..10 or 1..=9
+type RangePattern struct {
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (rp *RangePattern) patternNode() {}
+
+// TokenLiteral implements Node for RangePattern
+func (rp *RangePattern) TokenLiteral() string {
+	return rp.Start.TokenLiteral()
+}
+
+// String implements Node for RangePattern
+func (rp *RangePattern) String() string {
+	op := lexer.RANGE
+	if rp.Inclusive {
+		op = lexer.INCLRANGE
+	}
+	return rp.Start.String() + op + rp.End.String()
+}
+
+// Context implements Node for RangePattern
+func (rp *RangePattern) Context() lexer.Context {
+	return span(rp.Start.Context(), rp.End)
+}
+
+//*----------| ClassPattern |----------*/
+
+// ClassPattern matches instances of a class, destructuring their fields
+// as a MapPattern does, as in Point{x, y: 0}
+type ClassPattern struct {
+	Class  *Identifier
+	Fields *MapPattern
+}
+
+func (cp *ClassPattern) patternNode() {}
+
+// TokenLiteral implements Node for ClassPattern
+func (cp *ClassPattern) TokenLiteral() string {
+	return cp.Class.TokenLiteral()
+}
+
+// String implements Node for ClassPattern
+func (cp *ClassPattern) String() string {
+	return cp.Class.String() + cp.Fields.String()
+}
+
+// Context implements Node for ClassPattern
+func (cp *ClassPattern) Context() lexer.Context {
+	return span(cp.Class.Context(), cp.Fields)
+}
+
+//*----------| DefaultParam |----------*/
+
+// DefaultParam is a function parameter with a default value, as in
+// fn(a, b = 2). The default is evaluated when a call does not pass the
+// parameter, after the parameters before it have been bound.
+type DefaultParam struct {
+	Param   Pattern
+	Default Expression
+}
+
+func (dp *DefaultParam) patternNode() {}
+
+// TokenLiteral implements Node for DefaultParam
+func (dp *DefaultParam) TokenLiteral() string {
+	return dp.Param.TokenLiteral()
+}
+
+// String implements Node for DefaultParam
+func (dp *DefaultParam) String() string {
+	return dp.Param.String() + " = " + dp.Default.String()
+}
+
+// Context implements Node for DefaultParam
+func (dp *DefaultParam) Context() lexer.Context {
+	return span(dp.Param.Context(), dp.Default)
+}
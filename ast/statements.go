@@ -2,6 +2,7 @@ package ast
 
 import (
 	"bytes"
+	"strings"
 
 	"github.com/cartoon-raccoon/lemur/lexer"
 )
@@ -10,7 +11,9 @@ import (
 type LetStatement struct {
 	Token lexer.Token
 	Name  *Identifier
-	Value Expression
+	// Pattern is set instead of Name when the value is destructured
+	Pattern Pattern
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -24,7 +27,11 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.Value + " ")
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String() + " ")
+	} else {
+		out.WriteString(ls.Name.Value + " ")
+	}
 	out.WriteString("= ")
 
 	if ls.Value != nil {
@@ -38,7 +45,7 @@ func (ls *LetStatement) String() string {
 
 // Context implements Node for LetStatement
 func (ls *LetStatement) Context() lexer.Context {
-	return ls.Token.Pos
+	return span(ls.Token.Pos, ls.Value)
 }
 
 // ExprStatement - represents a bare expression in Monkey
@@ -64,7 +71,7 @@ func (es *ExprStatement) String() string {
 
 // Context implements Node for ExprStatement
 func (es *ExprStatement) Context() lexer.Context {
-	return es.Token.Pos
+	return span(es.Token.Pos, es.Expression)
 }
 
 // ReturnStatement - represents a return statement in the AST
@@ -95,13 +102,15 @@ func (rs *ReturnStatement) String() string {
 
 // Context implements Node for ReturnStatement
 func (rs *ReturnStatement) Context() lexer.Context {
-	return rs.Token.Pos
+	return span(rs.Token.Pos, rs.Value)
 }
 
 // BlockStatement represents a block of statements surrounded by braces
 type BlockStatement struct {
 	Token      lexer.Token
 	Statements []Statement
+	// Close is the closing brace of the block
+	Close lexer.Token
 }
 
 func (bs *BlockStatement) statementNode() {}
@@ -127,17 +136,20 @@ func (bs *BlockStatement) String() string {
 
 // Context implements Node for BlockStatement
 func (bs *BlockStatement) Context() lexer.Context {
-	return bs.Token.Pos
+	return closeSpan(bs.Token.Pos, bs.Close)
 }
 
 // WhileStatement represents a while loop
 type WhileStatement struct {
-	Token     lexer.Token
+	Token lexer.Token
+	// Label is the name given to the loop, or nil if it has none
+	Label     *Identifier
 	Condition Expression
 	Body      *BlockStatement
 }
 
-func (ws *WhileStatement) statementNode() {}
+func (ws *WhileStatement) statementNode()  {}
+func (ws *WhileStatement) expressionNode() {}
 
 // TokenLiteral implements Node for WhileStatement
 func (ws *WhileStatement) TokenLiteral() string {
@@ -148,6 +160,7 @@ func (ws *WhileStatement) TokenLiteral() string {
 func (ws *WhileStatement) String() string {
 	var out bytes.Buffer
 
+	out.WriteString(labelString(ws.Label))
 	out.WriteString("while ")
 	out.WriteString(ws.Condition.String())
 	out.WriteString(ws.Body.String())
@@ -157,12 +170,113 @@ func (ws *WhileStatement) String() string {
 
 // Context implements Node for WhileStatement
 func (ws *WhileStatement) Context() lexer.Context {
-	return ws.Token.Pos
+	start := labelStart(ws.Label, ws.Token)
+	if ws.Body == nil {
+		return span(start, ws.Condition)
+	}
+	return span(start, ws.Body)
+}
+
+// ForStatement represents a for-in loop
+type ForStatement struct {
+	Token lexer.Token
+	// Label is the name given to the loop, or nil if it has none
+	Label *Identifier
+	// Vars holds the loop variables, either the element alone or
+	// the index or key followed by the element
+	Vars     []Pattern
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()  {}
+func (fs *ForStatement) expressionNode() {}
+
+// TokenLiteral implements Node for ForStatement
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+// String implements Node for ForStatement
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	vars := []string{}
+	for _, v := range fs.Vars {
+		vars = append(vars, v.String())
+	}
+
+	out.WriteString(labelString(fs.Label))
+	out.WriteString("for ")
+	out.WriteString(strings.Join(vars, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// Context implements Node for ForStatement
+func (fs *ForStatement) Context() lexer.Context {
+	start := labelStart(fs.Label, fs.Token)
+	if fs.Body == nil {
+		return span(start, fs.Iterable)
+	}
+	return span(start, fs.Body)
+}
+
+// LoopStatement represents an infinite loop
+type LoopStatement struct {
+	Token lexer.Token
+	// Label is the name given to the loop, or nil if it has none
+	Label *Identifier
+	Body  *BlockStatement
+}
+
+func (ls *LoopStatement) statementNode()  {}
+func (ls *LoopStatement) expressionNode() {}
+
+// TokenLiteral implements Node for LoopStatement
+func (ls *LoopStatement) TokenLiteral() string {
+	return ls.Token.Literal
+}
+
+// String implements Node for LoopStatement
+func (ls *LoopStatement) String() string {
+	return labelString(ls.Label) + "loop " + ls.Body.String()
+}
+
+// Context implements Node for LoopStatement
+func (ls *LoopStatement) Context() lexer.Context {
+	start := labelStart(ls.Label, ls.Token)
+	if ls.Body == nil {
+		return start
+	}
+	return span(start, ls.Body)
+}
+
+func labelString(label *Identifier) string {
+	if label == nil {
+		return ""
+	}
+	return label.Value + ": "
+}
+
+// labelStart returns where a loop starts, which is at its label if it has one
+func labelStart(label *Identifier, tok lexer.Token) lexer.Context {
+	if label == nil {
+		return tok.Pos
+	}
+	return label.Token.Pos
 }
 
 // BreakStatement represents a break statement
 type BreakStatement struct {
 	Token lexer.Token
+	// Label is the loop being broken out of, or nil for the innermost loop
+	Label *Identifier
+	// Value is what the loop evaluates to, or nil if there is none
+	Value Expression
 }
 
 func (bs *BreakStatement) statementNode() {}
@@ -174,10 +288,93 @@ func (bs *BreakStatement) TokenLiteral() string {
 
 // String implements Node for BreakStatement
 func (bs *BreakStatement) String() string {
-	return bs.Token.Literal
+	var out bytes.Buffer
+
+	out.WriteString(bs.Token.Literal)
+	if bs.Label != nil {
+		out.WriteString(" " + bs.Label.Value)
+	}
+	if bs.Value != nil {
+		out.WriteString(" " + bs.Value.String())
+	}
+
+	return out.String()
 }
 
 // Context implements Node for BreakStatement
 func (bs *BreakStatement) Context() lexer.Context {
+	if bs.Value != nil {
+		return span(bs.Token.Pos, bs.Value)
+	}
+	if bs.Label != nil {
+		return span(bs.Token.Pos, bs.Label)
+	}
 	return bs.Token.Pos
 }
+
+// ContinueStatement represents a continue statement
+type ContinueStatement struct {
+	Token lexer.Token
+	// Label is the loop being continued, or nil for the innermost loop
+	Label *Identifier
+}
+
+func (cs *ContinueStatement) statementNode() {}
+
+// TokenLiteral implements Node for ContinueStatement
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+
+// String implements Node for ContinueStatement
+func (cs *ContinueStatement) String() string {
+	if cs.Label != nil {
+		return cs.Token.Literal + " " + cs.Label.Value
+	}
+	return cs.Token.Literal
+}
+
+// Context implements Node for ContinueStatement
+func (cs *ContinueStatement) Context() lexer.Context {
+	if cs.Label != nil {
+		return span(cs.Token.Pos, cs.Label)
+	}
+	return cs.Token.Pos
+}
+
+//*----------| ImportStatement |----------*/
+
+// ImportStatement represents an import of one or more modules
+type ImportStatement struct {
+	Token lexer.Token
+	Names []*Identifier
+	// Close is the closing paren of a parenthesised list, if there is one
+	Close lexer.Token
+}
+
+func (is *ImportStatement) statementNode() {}
+
+// TokenLiteral implements Node for ImportStatement
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+// String implements Node for ImportStatement
+func (is *ImportStatement) String() string {
+	names := []string{}
+	for _, name := range is.Names {
+		names = append(names, name.String())
+	}
+	if is.Close.Type == "" {
+		return is.Token.Literal + " " + strings.Join(names, ", ")
+	}
+	return is.Token.Literal + " (" + strings.Join(names, ", ") + ")"
+}
+
+// Context implements Node for ImportStatement
+func (is *ImportStatement) Context() lexer.Context {
+	if is.Close.Type != "" {
+		return closeSpan(is.Token.Pos, is.Close)
+	}
+	return span(is.Token.Pos, is.Names[len(is.Names)-1])
+}
@@ -49,6 +49,55 @@ const (
 	OpMinus
 	// OpBang - For boolean negation
 	OpBang
+	// OpConcat - Pops the number of values given by its operand and pushes
+	// a string joining them together, for interpolated strings
+	OpConcat
+	// OpGetGlobal - Pushes the global variable with the index given by its operand
+	OpGetGlobal
+	// OpSetGlobal - Pops the topmost value and binds it to a global variable
+	OpSetGlobal
+	// OpArray - Pops the number of values given by its operand into an array
+	OpArray
+	// OpMap - Pops the number of values given by its operand, as alternating
+	// keys and values, into a map
+	OpMap
+	// OpIndex - Pops an index and the value being indexed, and pushes the element
+	OpIndex
+	// OpSetIndex - Pops a value, an index and the value being indexed,
+	// sets the element at the index and pushes the value
+	OpSetIndex
+	// OpDup - Pushes copies of the number of topmost values given by its operand
+	OpDup
+	// OpNull - Pushes null to the stack
+	OpNull
+	// OpJump - Jumps to the instruction at its operand
+	OpJump
+	// OpJumpNotTruthy - Pops a value and jumps to its operand if the value is not truthy
+	OpJumpNotTruthy
+	// OpIter - Pops a collection and pushes an iterator over it. Its operand is the
+	// number of loop variables, which decides what a map iterates over
+	OpIter
+	// OpSwap - Swaps the two topmost values on the stack
+	OpSwap
+	// OpRange - Pops a step, an end and a start, any of which may be null, and
+	// pushes a range. The range includes its end if the operand is 1
+	OpRange
+	// OpSlice - Pops a step, an end, a start and the value being sliced, and
+	// pushes the slice. The slice includes its end if the operand is 1
+	OpSlice
+	// OpIterNext - Pushes the index or key and the element of the next item from the
+	// iterator on top of the stack, or jumps to its operand if there are none left
+	OpIterNext
+	// OpSet - Pops the number of values given by its operand into a set
+	OpSet
+	// OpIn - Pops a collection and a value, and pushes whether the value is in it
+	OpIn
+	// OpMatchArray - Pops a value and pushes whether it is an array with the length
+	// given by its first operand, or at least that length if the second operand is 1
+	OpMatchArray
+	// OpMatchKey - Pops a value and pushes whether it is a map that has the key
+	// in the constant given by its operand
+	OpMatchKey
 )
 
 // Definition defines a single instruction - opcode and operand widths
@@ -80,6 +129,29 @@ var definitions = map[Opcode]*Definition{
 	OpGE:    {"OpGE", 1, []int{}},
 	OpMinus: {"OpMinus", 1, []int{}},
 	OpBang:  {"OpBang", 1, []int{}},
+
+	OpConcat:    {"OpConcat", 3, []int{2}},
+	OpGetGlobal: {"OpGetGlobal", 3, []int{2}},
+	OpSetGlobal: {"OpSetGlobal", 3, []int{2}},
+	OpArray:     {"OpArray", 3, []int{2}},
+	OpMap:       {"OpMap", 3, []int{2}},
+	OpSet:       {"OpSet", 3, []int{2}},
+	OpIn:        {"OpIn", 1, []int{}},
+	OpIndex:     {"OpIndex", 1, []int{}},
+	OpSetIndex:  {"OpSetIndex", 1, []int{}},
+	OpDup:       {"OpDup", 3, []int{2}},
+	OpNull:      {"OpNull", 1, []int{}},
+
+	OpJump:          {"OpJump", 3, []int{2}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", 3, []int{2}},
+	OpIter:          {"OpIter", 3, []int{2}},
+	OpIterNext:      {"OpIterNext", 3, []int{2}},
+	OpSwap:          {"OpSwap", 1, []int{}},
+	OpRange:         {"OpRange", 3, []int{2}},
+	OpSlice:         {"OpSlice", 3, []int{2}},
+
+	OpMatchArray: {"OpMatchArray", 5, []int{2, 2}},
+	OpMatchKey:   {"OpMatchKey", 3, []int{2}},
 }
 
 // Lookup gets the definition of an Opcode
@@ -156,6 +228,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	default:
 		return fmt.Sprintf("Error: unhandled operand count for %s\n", def.Name)
 	}
@@ -12,6 +12,7 @@ func TestEncode(t *testing.T) {
 		{OpAdd, []int{}, []byte{byte(OpAdd)}},
 		{OpSub, []int{}, []byte{byte(OpSub)}},
 		{OpPop, []int{}, []byte{byte(OpPop)}},
+		{OpMatchArray, []int{2, 1}, []byte{byte(OpMatchArray), 0, 2, 0, 1}},
 	}
 
 	for idx, test := range tests {
@@ -36,12 +37,16 @@ func TestInstructionsString(t *testing.T) {
 		Encode(OpPop),
 		Encode(OpPush, 2),
 		Encode(OpPush, 65535),
+		Encode(OpConcat, 3),
+		Encode(OpMatchArray, 2, 1),
 	}
 	expected := `0000 OpSub
 0001 OpAdd
 0002 OpPop
-0003 OpConstant 2
-0006 OpConstant 65535
+0003 OpPush 2
+0006 OpPush 65535
+0009 OpConcat 3
+0012 OpMatchArray 2 1
 `
 
 	concatted := Instructions{}
@@ -13,6 +13,30 @@ import (
 type Compiler struct {
 	instructions code.Instructions
 	constants    []object.Object
+	symbols      *SymbolTable
+
+	lastInstruction EmittedInstruction
+	// loops holds the loops enclosing the code being compiled, innermost last
+	loops []*loop
+}
+
+// EmittedInstruction is an instruction that has been emitted by the compiler
+type EmittedInstruction struct {
+	Opcode   code.Opcode
+	Position int
+}
+
+// loop tracks the jumps of a loop being compiled
+type loop struct {
+	label string
+	// iterates is whether the loop is a for-in loop,
+	// which keeps an iterator on the stack
+	iterates bool
+	// start is the position continue jumps to
+	start int
+	// breaks holds the positions of the jumps out of the loop,
+	// which are patched once the end of the loop is known
+	breaks []int
 }
 
 // New returns a new compiler struct
@@ -20,25 +44,207 @@ func New() *Compiler {
 	return &Compiler{
 		instructions: code.Instructions{},
 		constants:    []object.Object{},
+		symbols:      NewSymbolTable(),
 	}
 }
 
+// NewWithState returns a compiler that carries on from the symbols and
+// constants of an earlier compilation, so that globals persist across
+// programs run one after another, as in the REPL
+func NewWithState(symbols *SymbolTable, constants []object.Object) *Compiler {
+	c := New()
+	c.symbols = symbols
+	c.constants = constants
+	return c
+}
+
 // Compile is the main compiler function and does all the heavy lifting
 func (c *Compiler) Compile(node ast.Node) error {
 	switch node := node.(type) {
 	case *ast.Program:
+		if len(node.Functions) > 0 || len(node.Classes) > 0 || len(node.Traits) > 0 || len(node.Impls) > 0 {
+			return fmt.Errorf("declarations are not supported by the compiler yet")
+		}
+		for _, s := range node.Statements {
+			err := c.compileStatement(s)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.BlockStatement:
 		for _, s := range node.Statements {
-			err := c.Compile(s)
+			err := c.compileStatement(s)
+			if err != nil {
+				return err
+			}
+		}
+	case *ast.WhileStatement:
+		start := len(c.instructions)
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		exit := c.emit(code.OpJumpNotTruthy, 9999)
+
+		current := c.enterLoop(node.Label, start, false)
+		err = c.compileLoopBody(start, node.Body)
+		if err != nil {
+			return err
+		}
+		// a while loop that ends without breaking evaluates to null
+		c.changeOperand(exit, len(c.instructions))
+		c.emit(code.OpNull)
+		c.leaveLoop(current)
+	case *ast.LoopStatement:
+		start := len(c.instructions)
+		current := c.enterLoop(node.Label, start, false)
+		err := c.compileLoopBody(start, node.Body)
+		if err != nil {
+			return err
+		}
+		c.leaveLoop(current)
+	case *ast.ForStatement:
+		return c.compileFor(node)
+	case *ast.MatchExpr:
+		return c.compileMatch(node)
+	case *ast.BreakStatement:
+		target, err := c.findLoop("break", node.Label)
+		if err != nil {
+			return err
+		}
+		if node.Value != nil {
+			err = c.Compile(node.Value)
 			if err != nil {
 				return err
 			}
+		} else {
+			c.emit(code.OpNull)
+		}
+		// the iterators of the loops being left are below the value
+		for i := len(c.loops) - 1; i >= target; i-- {
+			if c.loops[i].iterates {
+				c.emit(code.OpSwap)
+				c.emit(code.OpPop)
+			}
+		}
+		c.loops[target].breaks = append(c.loops[target].breaks, c.emit(code.OpJump, 9999))
+	case *ast.ContinueStatement:
+		target, err := c.findLoop("continue", node.Label)
+		if err != nil {
+			return err
+		}
+		for i := len(c.loops) - 1; i > target; i-- {
+			if c.loops[i].iterates {
+				c.emit(code.OpPop)
+			}
 		}
+		c.emit(code.OpJump, c.loops[target].start)
 	case *ast.ExprStatement:
 		err := c.Compile(node.Expression)
 		if err != nil {
 			return err
 		}
 		c.emit(code.OpPop)
+	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return fmt.Errorf("destructuring is not supported by the compiler yet: %s", node.String())
+		}
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		symbol := c.symbols.Define(node.Name.Value)
+		c.emit(code.OpSetGlobal, symbol.Index)
+	case *ast.Identifier:
+		symbol, ok := c.symbols.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Value)
+		}
+		c.emit(code.OpGetGlobal, symbol.Index)
+	case *ast.AssignExpr:
+		return c.compileAssign(node)
+	case *ast.Tuple:
+		return fmt.Errorf("tuples are not supported by the compiler yet: %s", node.String())
+	case *ast.ImportStatement, *ast.DotExpression:
+		return fmt.Errorf("modules are not supported by the compiler yet: %s", node.String())
+	case *ast.IndexExpr:
+		err := c.Compile(node.Left)
+		if err != nil {
+			return err
+		}
+		if rng, ok := node.Index.(*ast.RangeExpr); ok {
+			err = c.compileRangeBounds(rng)
+			if err != nil {
+				return err
+			}
+			c.emit(code.OpSlice, boolOperand(rng.Inclusive))
+			return nil
+		}
+		err = c.Compile(node.Index)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIndex)
+	case *ast.RangeExpr:
+		err := c.compileRangeBounds(node)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpRange, boolOperand(node.Inclusive))
+	case *ast.Array:
+		for _, elem := range node.Elements {
+			err := c.Compile(elem)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpArray, len(node.Elements))
+	case *ast.Map:
+		// the elements are compiled in source order
+		for _, key := range node.Keys {
+			err := c.Compile(key)
+			if err != nil {
+				return err
+			}
+			err = c.Compile(node.Elements[key])
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpMap, len(node.Keys)*2)
+	case *ast.Set:
+		for _, elem := range node.Elements {
+			err := c.Compile(elem)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSet, len(node.Elements))
+	case *ast.IfExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		jumpNotTruthy := c.emit(code.OpJumpNotTruthy, 9999)
+		err = c.compileBlockValue(node.Result)
+		if err != nil {
+			return err
+		}
+		jump := c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthy, len(c.instructions))
+
+		switch alt := node.Alternative.(type) {
+		case nil:
+			c.emit(code.OpNull)
+		case *ast.BlockStatement:
+			err = c.compileBlockValue(alt)
+		default:
+			err = c.Compile(alt)
+		}
+		if err != nil {
+			return err
+		}
+		c.changeOperand(jump, len(c.instructions))
 	case *ast.PrefixExpr:
 		err := c.Compile(node.Right)
 		if err != nil {
@@ -79,32 +285,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		switch node.Operator {
-		case lexer.ADD:
-			c.emit(code.OpAdd)
-		case lexer.SUB:
-			c.emit(code.OpSub)
-		case lexer.MUL:
-			c.emit(code.OpMul)
-		case lexer.DIV:
-			c.emit(code.OpDiv)
-		case lexer.BWAND:
-			c.emit(code.OpBWAnd)
-		case lexer.BWOR:
-			c.emit(code.OpBWOr)
-		case lexer.BWNOT:
-			c.emit(code.OpBWXOR)
-		case lexer.EQ:
-			c.emit(code.OpEq)
-		case lexer.NE:
-			c.emit(code.OpNE)
-		case lexer.GT:
-			c.emit(code.OpGT)
-		case lexer.GE:
-			c.emit(code.OpGE)
-		default:
-			return fmt.Errorf("unknown operator: %s", node.Operator)
-		}
+		return c.emitOperator(node.Operator)
 	case *ast.Int:
 		integer := &object.Integer{Value: node.Inner}
 		c.emit(code.OpPush, c.addConstant(integer))
@@ -114,22 +295,316 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.Str:
 		str := &object.String{Value: node.Inner}
 		c.emit(code.OpPush, c.addConstant(str))
+	case *ast.InterpStr:
+		for _, part := range node.Parts {
+			err := c.Compile(part)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpConcat, len(node.Parts))
 	case *ast.Bool:
 		if node.Inner {
 			c.emit(code.OpTrue)
 		} else {
 			c.emit(code.OpFalse)
 		}
+	case *ast.FnLiteral, *ast.FunctionCall, *ast.ReturnStatement:
+		return fmt.Errorf("functions are not supported by the compiler yet: %s", node.String())
+	default:
+		return fmt.Errorf("%s is not supported by the compiler yet", node.String())
+	}
+	return nil
+}
+
+// emitOperator emits the instruction for a binary operator,
+// other than < and <= which are compiled by swapping their operands
+func (c *Compiler) emitOperator(op string) error {
+	switch op {
+	case lexer.ADD:
+		c.emit(code.OpAdd)
+	case lexer.SUB:
+		c.emit(code.OpSub)
+	case lexer.MUL:
+		c.emit(code.OpMul)
+	case lexer.DIV:
+		c.emit(code.OpDiv)
+	case lexer.BWAND:
+		c.emit(code.OpBWAnd)
+	case lexer.BWOR:
+		c.emit(code.OpBWOr)
+	case lexer.BWNOT:
+		c.emit(code.OpBWXOR)
+	case lexer.EQ:
+		c.emit(code.OpEq)
+	case lexer.NE:
+		c.emit(code.OpNE)
+	case lexer.GT:
+		c.emit(code.OpGT)
+	case lexer.GE:
+		c.emit(code.OpGE)
+	case lexer.IN:
+		c.emit(code.OpIn)
+	default:
+		return fmt.Errorf("unknown operator: %s", op)
+	}
+	return nil
+}
+
+// compileAssign compiles an assignment, leaving the assigned value on the stack
+func (c *Compiler) compileAssign(node *ast.AssignExpr) error {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbols.Resolve(target.Value)
+		if !ok {
+			return fmt.Errorf("cannot assign to undeclared variable %s", target.Value)
+		}
+		if node.Operator != "" {
+			c.emit(code.OpGetGlobal, symbol.Index)
+		}
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		if node.Operator != "" {
+			err = c.emitOperator(node.Operator)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSetGlobal, symbol.Index)
+		c.emit(code.OpGetGlobal, symbol.Index)
+
+	case *ast.IndexExpr:
+		err := c.Compile(target.Left)
+		if err != nil {
+			return err
+		}
+		err = c.Compile(target.Index)
+		if err != nil {
+			return err
+		}
+		if node.Operator != "" {
+			// the collection and index are needed again to store the result
+			c.emit(code.OpDup, 2)
+			c.emit(code.OpIndex)
+		}
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		if node.Operator != "" {
+			err = c.emitOperator(node.Operator)
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSetIndex)
+
+	default:
+		return fmt.Errorf("cannot assign to %s", node.Target.String())
 	}
 	return nil
 }
 
+// compileRangeBounds compiles the start, end and step of a range,
+// pushing null for any that were left out
+func (c *Compiler) compileRangeBounds(rng *ast.RangeExpr) error {
+	for _, bound := range []ast.Expression{rng.Start, rng.End, rng.Step} {
+		if bound == nil {
+			c.emit(code.OpNull)
+			continue
+		}
+		err := c.Compile(bound)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func boolOperand(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compileStatement compiles a statement in a block or program. Loops
+// are expressions, so the value of a loop used as a statement is popped.
+func (c *Compiler) compileStatement(s ast.Statement) error {
+	err := c.Compile(s)
+	if err != nil {
+		return err
+	}
+	if isLoop(s) {
+		c.emit(code.OpPop)
+	}
+	return nil
+}
+
+func isLoop(s ast.Statement) bool {
+	switch s.(type) {
+	case *ast.WhileStatement, *ast.ForStatement, *ast.LoopStatement:
+		return true
+	default:
+		return false
+	}
+}
+
+// compileBlockValue compiles a block whose value is left on the stack,
+// which is the value of its last statement if that is an expression
+func (c *Compiler) compileBlockValue(block *ast.BlockStatement) error {
+	err := c.Compile(block)
+	if err != nil {
+		return err
+	}
+	last := len(block.Statements) - 1
+	if last >= 0 && c.lastInstruction.Opcode == code.OpPop {
+		if _, ok := block.Statements[last].(*ast.ExprStatement); ok || isLoop(block.Statements[last]) {
+			c.removeLastPop()
+			return nil
+		}
+	}
+	c.emit(code.OpNull)
+	return nil
+}
+
+// enterLoop starts tracking a loop, so that break and continue can find it
+func (c *Compiler) enterLoop(label *ast.Identifier, start int, iterates bool) *loop {
+	current := &loop{start: start, iterates: iterates}
+	if label != nil {
+		current.label = label.Value
+	}
+	c.loops = append(c.loops, current)
+	return current
+}
+
+// leaveLoop stops tracking a loop, pointing its breaks at the current position
+func (c *Compiler) leaveLoop(current *loop) {
+	c.loops = c.loops[:len(c.loops)-1]
+	for _, pos := range current.breaks {
+		c.changeOperand(pos, len(c.instructions))
+	}
+}
+
+// findLoop returns the index in c.loops of the loop that a break
+// or continue with the given label refers to
+func (c *Compiler) findLoop(stmt string, label *ast.Identifier) (int, error) {
+	if len(c.loops) == 0 {
+		return 0, fmt.Errorf("cannot use %s outside of loop", stmt)
+	}
+	if label == nil {
+		return len(c.loops) - 1, nil
+	}
+	for i := len(c.loops) - 1; i >= 0; i-- {
+		if c.loops[i].label == label.Value {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown loop label %s", label.Value)
+}
+
+// compileLoopBody compiles the body of a loop that starts at start,
+// jumping back to the start at the end of the body
+func (c *Compiler) compileLoopBody(start int, body *ast.BlockStatement) error {
+	err := c.Compile(body)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpJump, start)
+	return nil
+}
+
+// compileFor compiles a for-in loop. The iterator stays on the stack
+// while the loop runs and is popped at the end.
+func (c *Compiler) compileFor(node *ast.ForStatement) error {
+	err := c.Compile(node.Iterable)
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for _, v := range node.Vars {
+		ident, ok := v.(*ast.Identifier)
+		if !ok {
+			return fmt.Errorf("destructuring is not supported by the compiler yet: %s", v.String())
+		}
+		names = append(names, ident.Value)
+	}
+	c.emit(code.OpIter, len(names))
+
+	// the loop variables shadow any existing variables until the loop ends
+	vars, restore := c.defineScoped(names)
+	defer restore()
+
+	start := c.emit(code.OpIterNext, 9999)
+	// the element is on top of the stack, above the index or key
+	c.emit(code.OpSetGlobal, vars[len(vars)-1].Index)
+	if len(vars) == 2 {
+		c.emit(code.OpSetGlobal, vars[0].Index)
+	} else {
+		c.emit(code.OpPop)
+	}
+
+	current := c.enterLoop(node.Label, start, true)
+	err = c.compileLoopBody(start, node.Body)
+	if err != nil {
+		return err
+	}
+	// once the iterator runs out, it is replaced by null as the loop's value
+	c.changeOperand(start, len(c.instructions))
+	c.emit(code.OpPop)
+	c.emit(code.OpNull)
+	c.leaveLoop(current)
+
+	return nil
+}
+
+// defineScoped defines names that shadow any existing variables with the
+// same names, and returns their symbols along with a function that restores
+// the shadowed variables once the names go out of scope
+func (c *Compiler) defineScoped(names []string) ([]Symbol, func()) {
+	shadowed := make(map[string]Symbol)
+	for _, name := range names {
+		if symbol, ok := c.symbols.Resolve(name); ok {
+			shadowed[name] = symbol
+		}
+	}
+
+	symbols := []Symbol{}
+	for _, name := range names {
+		symbols = append(symbols, c.symbols.Define(name))
+	}
+
+	return symbols, func() {
+		for _, name := range names {
+			if symbol, ok := shadowed[name]; ok {
+				c.symbols.store[name] = symbol
+			} else {
+				delete(c.symbols.store, name)
+			}
+		}
+	}
+}
+
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Encode(op, operands...)
 	pos := c.addInstruction(ins)
+	c.lastInstruction = EmittedInstruction{Opcode: op, Position: pos}
 	return pos
 }
 
+func (c *Compiler) removeLastPop() {
+	c.instructions = c.instructions[:c.lastInstruction.Position]
+}
+
+// changeOperand replaces the operand of the instruction at pos,
+// for jumps whose target was not known when they were emitted
+func (c *Compiler) changeOperand(pos int, operand int) {
+	op := code.Opcode(c.instructions[pos])
+	copy(c.instructions[pos:], code.Encode(op, operand))
+}
+
 func (c *Compiler) addConstant(obj object.Object) int {
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
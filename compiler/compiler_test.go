@@ -33,6 +33,443 @@ func TestIntegerArithmetic(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"a${1 + 2}b"`,
+			expectedConstants: []interface{}{"a", 1, 2, "b"},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpPush, 2),
+				code.Encode(code.OpAdd),
+				code.Encode(code.OpPush, 3),
+				code.Encode(code.OpConcat, 3),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let one = 1; let two = one; two;",
+			expectedConstants: []interface{}{1},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpSetGlobal, 1),
+				code.Encode(code.OpGetGlobal, 1),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestAssignments(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let x = 1; x = 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "let x = 1; x -= 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpSub),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "let a = [1]; a[0] *= 3;",
+			expectedConstants: []interface{}{1, 0, 3},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpArray, 1),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpDup, 2),
+				code.Encode(code.OpIndex),
+				code.Encode(code.OpPush, 2),
+				code.Encode(code.OpMul),
+				code.Encode(code.OpSetIndex),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             `let m = {"b": 2, "a": 1}; m["a"] = 3;`,
+			expectedConstants: []interface{}{"b", 2, "a", 1, "a", 3},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpPush, 2),
+				code.Encode(code.OpPush, 3),
+				code.Encode(code.OpMap, 4),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPush, 4),
+				code.Encode(code.OpPush, 5),
+				code.Encode(code.OpSetIndex),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "if (true) { 10 }; 3333;",
+			expectedConstants: []interface{}{10, 3333},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpTrue),
+				code.Encode(code.OpJumpNotTruthy, 10),
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpJump, 11),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "if (true) { 10 } else { let x = 20; }",
+			expectedConstants: []interface{}{10, 20},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpTrue),
+				code.Encode(code.OpJumpNotTruthy, 10),
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpJump, 17),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { break; continue; }",
+			expectedConstants: []interface{}{},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpTrue),
+				code.Encode(code.OpJumpNotTruthy, 14),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpJump, 15),
+				code.Encode(code.OpJump, 0),
+				code.Encode(code.OpJump, 0),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "loop { break 5; }",
+			expectedConstants: []interface{}{5},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpJump, 9),
+				code.Encode(code.OpJump, 0),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "for x in [1] { x; }",
+			expectedConstants: []interface{}{1},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpArray, 1),
+				code.Encode(code.OpIter, 1),
+				code.Encode(code.OpIterNext, 23),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpGetGlobal, 0),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpJump, 9),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "for i, x in [] { continue; }",
+			expectedConstants: []interface{}{},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpArray, 0),
+				code.Encode(code.OpIter, 2),
+				code.Encode(code.OpIterNext, 21),
+				code.Encode(code.OpSetGlobal, 1),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpJump, 6),
+				code.Encode(code.OpJump, 6),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "outer: loop { for x in [] { continue outer; break outer; } }",
+			expectedConstants: []interface{}{},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpArray, 0),
+				code.Encode(code.OpIter, 1),
+				code.Encode(code.OpIterNext, 26),
+				code.Encode(code.OpSetGlobal, 0),
+				code.Encode(code.OpPop),
+				// continue outer drops the inner iterator
+				code.Encode(code.OpPop),
+				code.Encode(code.OpJump, 0),
+				// break outer swaps the value below the inner iterator and drops it
+				code.Encode(code.OpNull),
+				code.Encode(code.OpSwap),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpJump, 32),
+				code.Encode(code.OpJump, 6),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPop),
+				code.Encode(code.OpJump, 0),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestRanges(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[1..=2]",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpRange, 1),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "[1][..2]",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpArray, 1),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpNull),
+				code.Encode(code.OpSlice, 0),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestSets(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "{1, 2}",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpSet, 2),
+				code.Encode(code.OpPop),
+			},
+		},
+		{
+			input:             "1 in {2}",
+			expectedConstants: []interface{}{1, 2},
+			expectedInsts: []code.Instructions{
+				code.Encode(code.OpPush, 0),
+				code.Encode(code.OpPush, 1),
+				code.Encode(code.OpSet, 1),
+				code.Encode(code.OpIn),
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "match 1 { 1 => 2, _ => 3 }",
+			expectedConstants: []interface{}{1, 1, 2, 3},
+			expectedInsts: []code.Instructions{
+				// 0000
+				code.Encode(code.OpPush, 0),
+				// 0003
+				code.Encode(code.OpSetGlobal, 0),
+				// 0006
+				code.Encode(code.OpGetGlobal, 0),
+				// 0009
+				code.Encode(code.OpPush, 1),
+				// 0012
+				code.Encode(code.OpEq),
+				// 0013
+				code.Encode(code.OpJumpNotTruthy, 22),
+				// 0016
+				code.Encode(code.OpPush, 2),
+				// 0019
+				code.Encode(code.OpJump, 29),
+				// 0022
+				code.Encode(code.OpPush, 3),
+				// 0025
+				code.Encode(code.OpJump, 29),
+				// 0028
+				code.Encode(code.OpNull),
+				// 0029
+				code.Encode(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	for _, input := range []string{"break;", "continue;", "if (true) { break; }"} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestUndeclaredAssignment(t *testing.T) {
+	for _, input := range []string{"x = 1", "x += 1", "x"} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestUnsupportedModules(t *testing.T) {
+	for _, input := range []string{"import math", "let x = 1; x.y"} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestUnsupportedTuples(t *testing.T) {
+	for _, input := range []string{"(1, 2)", "let (a, b) = x;"} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestUnsupportedFunctions(t *testing.T) {
+	for _, input := range []string{
+		`let items = [1, 2]; "${len(items)}"`,
+		`let f = 1; "x${f(2)}y"`,
+		"let f = fn(x) { return x; };",
+		"fn f(x) { x }",
+	} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestUnsupportedPatterns(t *testing.T) {
+	for _, input := range []string{
+		"let [a, b] = x;",
+		"let {a} = x;",
+		"let x = [[1, 2]]; for [a, b] in x { a }",
+		"match 1 { (a, b) => a }",
+		"match 1 { Point { x } => x }",
+	} {
+		p, _ := parser.New(lexer.New(input))
+		prog := p.Parse()
+
+		err := New().Compile(prog)
+		if err == nil {
+			t.Errorf("Expected an error compiling %q", input)
+		}
+	}
+}
+
+func TestSymbolTable(t *testing.T) {
+	table := NewSymbolTable()
+
+	a, b := table.Define("a"), table.Define("b")
+	expected := map[string]Symbol{
+		"a": {Name: "a", Scope: GlobalScope, Index: 0},
+		"b": {Name: "b", Scope: GlobalScope, Index: 1},
+	}
+	if a != expected["a"] || b != expected["b"] {
+		t.Errorf("Expected %+v, got %+v and %+v", expected, a, b)
+	}
+
+	for name, symbol := range expected {
+		result, ok := table.Resolve(name)
+		if !ok {
+			t.Errorf("Name %s not resolvable", name)
+			continue
+		}
+		if result != symbol {
+			t.Errorf("Expected %s to resolve to %+v, got %+v", name, symbol, result)
+		}
+	}
+	if _, ok := table.Resolve("c"); ok {
+		t.Errorf("Expected undefined name to be unresolvable")
+	}
+}
+
 func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 	t.Helper()
 
@@ -64,6 +501,11 @@ func runCompilerTests(t *testing.T, tests []compilerTestCase) {
 		if err != nil {
 			t.Errorf("Error in instructions: %s", err)
 		}
+
+		err = testConstants(t, test.expectedConstants, bytecode.Constants)
+		if err != nil {
+			t.Errorf("Error in constants: %s", err)
+		}
 	}
 }
 
@@ -103,6 +545,11 @@ func testConstants(
 			if err != nil {
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
+		case string:
+			err := testStringObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testStringObject failed: %s", i, err)
+			}
 		}
 	}
 
@@ -132,3 +579,17 @@ func testIntegerObject(constant int64, obj object.Object) error {
 
 	return nil
 }
+
+func testStringObject(expected string, obj object.Object) error {
+	result, ok := obj.(*object.String)
+	if !ok {
+		return fmt.Errorf("Expected string, got %T", obj)
+	}
+
+	if result.Value != expected {
+		return fmt.Errorf("Values do not equate: got %q, expected %q",
+			result.Value, expected)
+	}
+
+	return nil
+}
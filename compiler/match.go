@@ -0,0 +1,246 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/code"
+	"github.com/cartoon-raccoon/lemur/object"
+)
+
+// matchSubject is the name of the hidden variable holding the subject of
+// a match. It is not a valid identifier, so it cannot clash with variables.
+const matchSubject = "<match>"
+
+// step is one step along the path from the subject of a match
+// to the part of it that a nested pattern is matched against
+type step struct {
+	// key is the key of a map value, or empty for an array element
+	key string
+	// index is the index of an array element, counting from the end if negative
+	index int
+	// rest is whether the step takes the elements of an array from index
+	// up to the last after elements, for a rest pattern
+	rest  bool
+	after int
+}
+
+// compileMatch compiles a match expression. The subject is kept in a hidden
+// variable, and every check that a pattern makes loads the part of the
+// subject it needs afresh. That way the stack is the same wherever a check
+// fails, and a failed check can jump straight to the next arm.
+func (c *Compiler) compileMatch(node *ast.MatchExpr) error {
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+	// the subject of a match nested in an arm shadows that of this one
+	symbols, restore := c.defineScoped([]string{matchSubject})
+	defer restore()
+	subject := symbols[0]
+	c.emit(code.OpSetGlobal, subject.Index)
+
+	ends := []int{}
+	for _, arm := range node.Arms {
+		end, err := c.compileMatchArm(arm, subject)
+		if err != nil {
+			return err
+		}
+		ends = append(ends, end)
+	}
+	// a match that no arm matches evaluates to null
+	c.emit(code.OpNull)
+	for _, pos := range ends {
+		c.changeOperand(pos, len(c.instructions))
+	}
+	return nil
+}
+
+// compileMatchArm compiles an arm of a match, which jumps to the next arm if
+// its pattern or guard fails. It returns the position of the jump that leaves
+// the match after its body, which is patched once the end is known.
+func (c *Compiler) compileMatchArm(arm *ast.MatchArm, subject Symbol) (int, error) {
+	// names bound by an arm are only visible in its guard and body
+	_, restore := c.defineScoped(patternNames(arm.Pattern))
+	defer restore()
+
+	fails := []int{}
+	err := c.compilePattern(arm.Pattern, subject, nil, &fails)
+	if err != nil {
+		return 0, err
+	}
+	if arm.Guard != nil {
+		err = c.Compile(arm.Guard)
+		if err != nil {
+			return 0, err
+		}
+		fails = append(fails, c.emit(code.OpJumpNotTruthy, 9999))
+	}
+
+	err = c.compileBlockValue(arm.Body)
+	if err != nil {
+		return 0, err
+	}
+	end := c.emit(code.OpJump, 9999)
+	for _, pos := range fails {
+		c.changeOperand(pos, len(c.instructions))
+	}
+	return end, nil
+}
+
+// compilePattern compiles the checks and bindings of a pattern matched
+// against the part of the subject at path. The positions of the jumps
+// taken when a check fails are added to fails.
+func (c *Compiler) compilePattern(pattern ast.Pattern, subject Symbol, path []step, fails *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		c.loadMatched(subject, path)
+		symbol, _ := c.symbols.Resolve(pattern.Value)
+		c.emit(code.OpSetGlobal, symbol.Index)
+
+	case *ast.WildcardPattern:
+
+	case *ast.LiteralPattern:
+		// comparing values of different types gives an exception,
+		// which is not truthy, so they do not match
+		c.loadMatched(subject, path)
+		err := c.Compile(pattern.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpEq)
+		*fails = append(*fails, c.emit(code.OpJumpNotTruthy, 9999))
+
+	case *ast.RangePattern:
+		c.loadMatched(subject, path)
+		err := c.Compile(pattern.Start)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpGE)
+		*fails = append(*fails, c.emit(code.OpJumpNotTruthy, 9999))
+
+		err = c.Compile(pattern.End)
+		if err != nil {
+			return err
+		}
+		c.loadMatched(subject, path)
+		if pattern.Inclusive {
+			c.emit(code.OpGE)
+		} else {
+			c.emit(code.OpGT)
+		}
+		*fails = append(*fails, c.emit(code.OpJumpNotTruthy, 9999))
+
+	case *ast.ArrayPattern:
+		rest := -1
+		for i, elem := range pattern.Elements {
+			if _, ok := elem.(*ast.RestPattern); ok {
+				rest = i
+			}
+		}
+		length := len(pattern.Elements)
+		if rest >= 0 {
+			length--
+		}
+		c.loadMatched(subject, path)
+		c.emit(code.OpMatchArray, length, boolOperand(rest >= 0))
+		*fails = append(*fails, c.emit(code.OpJumpNotTruthy, 9999))
+
+		for i, elem := range pattern.Elements {
+			next := step{index: i}
+			switch {
+			case i == rest:
+				next = step{index: i, rest: true, after: len(pattern.Elements) - i - 1}
+				elem = elem.(*ast.RestPattern).Name
+			case rest >= 0 && i > rest:
+				// patterns after the rest match from the end
+				next = step{index: i - len(pattern.Elements)}
+			}
+			err := c.compilePattern(elem, subject, extend(path, next), fails)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.MapPattern:
+		for i, key := range pattern.Keys {
+			c.loadMatched(subject, path)
+			c.emit(code.OpMatchKey, c.addConstant(&object.String{Value: key.Value}))
+			*fails = append(*fails, c.emit(code.OpJumpNotTruthy, 9999))
+
+			err := c.compilePattern(pattern.Values[i], subject, extend(path, step{key: key.Value}), fails)
+			if err != nil {
+				return err
+			}
+		}
+
+	case *ast.TuplePattern:
+		return fmt.Errorf("tuples are not supported by the compiler yet: %s", pattern.String())
+	case *ast.ClassPattern:
+		return fmt.Errorf("classes are not supported by the compiler yet: %s", pattern.String())
+	default:
+		return fmt.Errorf("unknown pattern %s", pattern.String())
+	}
+	return nil
+}
+
+// loadMatched pushes the part of the subject of a match at path
+func (c *Compiler) loadMatched(subject Symbol, path []step) {
+	c.emit(code.OpGetGlobal, subject.Index)
+	for _, s := range path {
+		switch {
+		case s.key != "":
+			c.emit(code.OpPush, c.addConstant(&object.String{Value: s.key}))
+			c.emit(code.OpIndex)
+		case s.rest:
+			c.emit(code.OpPush, c.addConstant(&object.Integer{Value: int64(s.index)}))
+			if s.after > 0 {
+				c.emit(code.OpPush, c.addConstant(&object.Integer{Value: int64(-s.after)}))
+			} else {
+				c.emit(code.OpNull)
+			}
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice, 0)
+		default:
+			c.emit(code.OpPush, c.addConstant(&object.Integer{Value: int64(s.index)}))
+			c.emit(code.OpIndex)
+		}
+	}
+}
+
+// extend returns a copy of path with next added to the end, so that
+// the paths of sibling patterns do not share a backing array
+func extend(path []step, next step) []step {
+	extended := make([]step, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, next)
+}
+
+// patternNames returns the names that a pattern binds, in order
+func patternNames(pattern ast.Pattern) []string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		return []string{pattern.Value}
+	case *ast.RestPattern:
+		return []string{pattern.Name.Value}
+	case *ast.ArrayPattern:
+		return elementNames(pattern.Elements)
+	case *ast.TuplePattern:
+		return elementNames(pattern.Elements)
+	case *ast.MapPattern:
+		return elementNames(pattern.Values)
+	case *ast.ClassPattern:
+		return patternNames(pattern.Fields)
+	default:
+		return nil
+	}
+}
+
+func elementNames(patterns []ast.Pattern) []string {
+	names := []string{}
+	for _, pattern := range patterns {
+		names = append(names, patternNames(pattern)...)
+	}
+	return names
+}
@@ -0,0 +1,42 @@
+package compiler
+
+// SymbolScope is the scope that a symbol is defined in
+type SymbolScope string
+
+const (
+	// GlobalScope - symbols defined at the top level of a program
+	GlobalScope SymbolScope = "GLOBAL"
+)
+
+// Symbol holds what the compiler knows about a name
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int
+}
+
+// SymbolTable maps the names defined in a program to symbols
+type SymbolTable struct {
+	store          map[string]Symbol
+	numDefinitions int
+}
+
+// NewSymbolTable returns a new, empty symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: make(map[string]Symbol)}
+}
+
+// Define adds a name to the symbol table and returns its symbol.
+// Redefining a name gives it a new index.
+func (s *SymbolTable) Define(name string) Symbol {
+	symbol := Symbol{Name: name, Scope: GlobalScope, Index: s.numDefinitions}
+	s.store[name] = symbol
+	s.numDefinitions++
+	return symbol
+}
+
+// Resolve looks up the symbol for a name
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	return symbol, ok
+}
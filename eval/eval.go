@@ -3,6 +3,7 @@ package eval
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/cartoon-raccoon/lemur/ast"
 	"github.com/cartoon-raccoon/lemur/lexer"
@@ -22,11 +23,15 @@ var (
 type Evaluator struct {
 	Ctxt      lexer.Context
 	loopcount int
+	loader    *Loader
+	// dir is the directory of the file being evaluated,
+	// which imports are resolved relative to
+	dir string
 }
 
 var builtins = map[string]*object.Builtin{
 	// Gets the number of items in a collection
-	// Can be called on strings, arrays and maps.
+	// Can be called on strings, arrays, tuples, maps, sets and ranges.
 	"len": {
 		Fn: func(ctxt lexer.Context, args ...object.Object) object.Object {
 			if len := len(args); len != 1 {
@@ -39,10 +44,22 @@ var builtins = map[string]*object.Builtin{
 			switch args[0].(type) {
 			case *object.String:
 				str := arg.(*object.String)
-				return &object.Integer{Value: int64(len(str.Value))}
+				return &object.Integer{Value: int64(len([]rune(str.Value)))}
 			case *object.Array:
 				arr := arg.(*object.Array)
 				return &object.Integer{Value: int64(len(arr.Elements))}
+			case *object.Tuple:
+				tuple := arg.(*object.Tuple)
+				return &object.Integer{Value: int64(len(tuple.Elements))}
+			case *object.Map:
+				hash := arg.(*object.Map)
+				return &object.Integer{Value: int64(len(hash.Elements))}
+			case *object.Set:
+				set := arg.(*object.Set)
+				return &object.Integer{Value: int64(len(set.Elements))}
+			case *object.Range:
+				rng := arg.(*object.Range)
+				return &object.Integer{Value: rng.Len()}
 			default:
 				return &object.Exception{
 					Msg: fmt.Sprintf("Cannot use type %T as argument for len()", arg),
@@ -93,6 +110,46 @@ var builtins = map[string]*object.Builtin{
 			return arr.Elements[0]
 		},
 	},
+	// Creates a range of integers for iterating over
+	// Takes an end, a start and end, or a start, end and step
+	"range": {
+		Fn: func(ctxt lexer.Context, args ...object.Object) object.Object {
+			if len := len(args); len < 1 || len > 3 {
+				return &object.Exception{
+					Msg: fmt.Sprintf("Expected 1 to 3 arguments for call to range(), got %d", len),
+					Con: ctxt,
+				}
+			}
+			bounds := []int64{}
+			for _, arg := range args {
+				num, ok := arg.(*object.Integer)
+				if !ok {
+					return &object.Exception{
+						Msg: fmt.Sprintf("Cannot use type %T as argument for range()", arg),
+						Con: ctxt,
+					}
+				}
+				bounds = append(bounds, num.Value)
+			}
+
+			rng := &object.Range{Step: 1}
+			switch len(bounds) {
+			case 1:
+				rng.End = bounds[0]
+			case 2:
+				rng.Start, rng.End = bounds[0], bounds[1]
+			case 3:
+				rng.Start, rng.End, rng.Step = bounds[0], bounds[1], bounds[2]
+			}
+			if rng.Step == 0 {
+				return &object.Exception{
+					Msg: "Step of range() cannot be zero",
+					Con: ctxt,
+				}
+			}
+			return rng
+		},
+	},
 	"print": {
 		Fn: func(ctxt lexer.Context, args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -138,11 +195,60 @@ var builtins = map[string]*object.Builtin{
 	},
 }
 
+// builtin looks up a builtin function. Most are in builtins, but set
+// is bound to the evaluator, as it calls the hash methods of instances.
+func (e *Evaluator) builtin(name string) (*object.Builtin, bool) {
+	if name == "set" {
+		return &object.Builtin{Fn: e.builtinSet}, true
+	}
+	bltn, ok := builtins[name]
+	return bltn, ok
+}
+
+// builtinSet creates a set, which is empty or holds the items of a
+// collection. As with iterating over it, the items of a map are its keys.
+func (e *Evaluator) builtinSet(ctxt lexer.Context, args ...object.Object) object.Object {
+	if len := len(args); len > 1 {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Expected 0 or 1 arguments for call to set(), got %d", len),
+			Con: ctxt,
+		}
+	}
+	set := object.NewSet()
+	if len(args) == 0 {
+		return set
+	}
+	iter := Iterate(args[0], 1, ctxt)
+	if object.IsErr(iter) {
+		return iter
+	}
+	for {
+		_, elem, ok := iter.(*object.Iterator).Next()
+		if !ok {
+			return set
+		}
+		elem = e.mapKey(elem, ctxt)
+		if object.IsErr(elem) {
+			return elem
+		}
+		hashable, ok := elem.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as element of Set", elem),
+				Con: ctxt,
+			}
+		}
+		set.Add(hashable)
+	}
+}
+
 // New - returns a new evaluator
 func New() *Evaluator {
 	eval := &Evaluator{
 		Ctxt:      lexer.Context{Line: 1, Col: 1, Ctxt: ""},
 		loopcount: 0,
+		loader:    NewLoader(SearchPath()...),
+		dir:       ".",
 	}
 	return eval
 }
@@ -155,16 +261,8 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 		res := &object.StmtResults{}
 		res.Results = []object.Object{}
 
-		// adding statements
-		for _, stmt := range node.(*ast.Program).Statements {
-			if ret, ok := stmt.(*ast.ReturnStatement); ok {
-				return e.Evaluate(ret, env)
-			}
-			result := e.Evaluate(stmt, env)
-			res.Results = append(res.Results, result)
-		}
-
-		// adding functions
+		// adding declarations first, so that they can be
+		// used from anywhere in the program
 		//todo: this should function differently than closures
 		for _, fn := range node.(*ast.Program).Functions {
 			body := fn.Body
@@ -175,8 +273,31 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 				Env:    env,
 			}
 		}
+		for _, trait := range node.(*ast.Program).Traits {
+			env.Data[trait.Name.Value] = newTrait(trait)
+		}
+		for _, class := range node.(*ast.Program).Classes {
+			env.Data[class.Name.Value] = newClass(class, env)
+		}
+		// impls are checked before anything runs
+		for _, impl := range node.(*ast.Program).Impls {
+			if exc := implement(impl, env); object.IsErr(exc) {
+				res.Results = append(res.Results, exc)
+				return res
+			}
+		}
 
-		//todo: adding classes
+		// adding statements, stopping at the first uncaught exception
+		for _, stmt := range node.(*ast.Program).Statements {
+			if ret, ok := stmt.(*ast.ReturnStatement); ok {
+				return e.Evaluate(ret, env)
+			}
+			result := e.Evaluate(stmt, env)
+			res.Results = append(res.Results, result)
+			if object.IsErr(result) {
+				break
+			}
+		}
 
 		return res
 
@@ -187,6 +308,12 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 		case *ast.LetStatement:
 			letstmt := stmt.(*ast.LetStatement)
 			val := e.Evaluate(letstmt.Value, env)
+			if object.IsErr(val) {
+				return val
+			}
+			if letstmt.Pattern != nil {
+				return bind(letstmt.Pattern, val, env)
+			}
 			env.Set(letstmt.Name.Value, val)
 			return NULL
 
@@ -200,42 +327,58 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 			return &object.Return{Inner: res}
 
 		case *ast.WhileStatement:
-			e.loopcount++
 			whilestmt := stmt.(*ast.WhileStatement)
+			return e.evalWhileStatement(whilestmt, env)
+
+		case *ast.LoopStatement:
+			loopstmt := stmt.(*ast.LoopStatement)
+			return e.evalLoopStatement(loopstmt, env)
 
-			var result object.Object
+		case *ast.ForStatement:
+			forstmt := stmt.(*ast.ForStatement)
+			return e.evalForStatement(forstmt, env)
 
-			for {
-				val := e.Evaluate(whilestmt.Condition, env)
-				if !evaluateTruthiness(val) {
-					break
+		case *ast.BreakStatement:
+			if e.loopcount == 0 {
+				return &object.Exception{
+					Msg: "Cannot use break outside of loop",
+					Con: node.(ast.Statement).Context(),
 				}
-				result = e.evalBlockStmt(whilestmt.Body, env)
-				if object.IsErr(result) || object.IsBreak(result) {
-					if object.IsBreak(result) {
-						e.loopcount--
-						return NULL
-					}
-					return result
+			}
+			brkstmt := stmt.(*ast.BreakStatement)
+			brk := &object.Break{}
+			if brkstmt.Label != nil {
+				brk.Label = brkstmt.Label.Value
+			}
+			if brkstmt.Value != nil {
+				brk.Value = e.Evaluate(brkstmt.Value, env)
+				if object.IsErr(brk.Value) {
+					return brk.Value
 				}
 			}
+			return brk
 
-			e.loopcount--
-			return result
-
-		case *ast.BreakStatement:
+		case *ast.ContinueStatement:
 			if e.loopcount == 0 {
 				return &object.Exception{
-					Msg: "Cannot use break outside of loop",
+					Msg: "Cannot use continue outside of loop",
 					Con: node.(ast.Statement).Context(),
 				}
 			}
-			return &object.Break{}
+			contstmt := stmt.(*ast.ContinueStatement)
+			if contstmt.Label != nil {
+				return &object.Continue{Label: contstmt.Label.Value}
+			}
+			return &object.Continue{}
 
 		case *ast.BlockStatement:
 			blkstmt := stmt.(*ast.BlockStatement)
 			return e.evalBlockStmt(blkstmt, env)
 
+		case *ast.ImportStatement:
+			impstmt := stmt.(*ast.ImportStatement)
+			return e.evalImportStatement(impstmt, env)
+
 		default:
 			return NULL
 		}
@@ -249,7 +392,7 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 			if data, ok := env.Get(ident.Value); ok {
 				return data
 			}
-			if bltn, ok := builtins[ident.Value]; ok {
+			if bltn, ok := e.builtin(ident.Value); ok {
 				return bltn
 			}
 			return &object.Exception{
@@ -274,7 +417,7 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 					Con: ifexpr.Context(),
 				}
 			}
-			if evaluateTruthiness(condition) {
+			if EvaluateTruthiness(condition) {
 				return e.Evaluate(ifexpr.Result, env)
 			}
 			if ifexpr.Alternative != nil {
@@ -290,6 +433,10 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 					}
 				}
 			}
+			return NULL
+
+		case *ast.MatchExpr:
+			return e.evalMatchExpr(expr.(*ast.MatchExpr), env)
 
 		case *ast.FnLiteral:
 			fnlit := expr.(*ast.FnLiteral)
@@ -307,19 +454,16 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 				return function
 			}
 
-			args := e.evalExpressions(fncall.Params, env)
-			if len(args) == 1 && object.IsErr(args[0]) {
-				return args[0]
+			args, kwargs, exc := e.evalArgs(fncall, env)
+			if exc != nil {
+				return exc
 			}
 
-			return e.applyFunction(function, args)
+			return e.applyFunction(function, args, kwargs)
 
 		case *ast.DotExpression:
-			//todo
-			return &object.Exception{
-				Msg: "DotExpr: unimplemented",
-				Con: node.Context(),
-			}
+			dotexpr := expr.(*ast.DotExpression)
+			return e.evalDotExpression(dotexpr, env)
 
 		case *ast.Int:
 			intexpr := node.(ast.Expression).(*ast.Int)
@@ -330,6 +474,9 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 		case *ast.Str:
 			strexpr := node.(ast.Expression).(*ast.Str)
 			return &object.String{Value: strexpr.Inner}
+		case *ast.InterpStr:
+			interp := node.(ast.Expression).(*ast.InterpStr)
+			return e.evalInterpStr(interp, env)
 		case *ast.Bool:
 			boolexpr := node.(ast.Expression).(*ast.Bool)
 			return nativeBooltoObj(boolexpr.Inner)
@@ -348,13 +495,20 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 
 			return arr
 
+		case *ast.Tuple:
+			tuple := node.(ast.Expression).(*ast.Tuple)
+			elements := e.evalExpressions(tuple.Elements, env)
+			if len(elements) == 1 && object.IsErr(elements[0]) {
+				return elements[0]
+			}
+			return &object.Tuple{Elements: elements}
+
 		case *ast.Map:
 			hash := node.(ast.Expression).(*ast.Map)
-			newmap := &object.Map{}
-			newmap.Elements = make(map[object.HashKey]object.Object)
+			newmap := object.NewMap()
 
-			for key, val := range hash.Elements {
-				nkey, nval := e.Evaluate(key, env), e.Evaluate(val, env)
+			for _, key := range hash.Keys {
+				nkey, nval := e.Evaluate(key, env), e.Evaluate(hash.Elements[key], env)
 
 				if object.IsErr(nkey) {
 					return nkey
@@ -363,6 +517,10 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 					return nval
 				}
 
+				nkey = e.mapKey(nkey, key.Context())
+				if object.IsErr(nkey) {
+					return nkey
+				}
 				hashable, ok := nkey.(object.Hashable)
 
 				if !ok {
@@ -372,15 +530,52 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 					}
 				}
 
-				newmap.Elements[hashable.HashKey()] = nval
+				newmap.Set(hashable, nval)
 			}
 
 			return newmap
 
+		case *ast.Set:
+			lit := node.(ast.Expression).(*ast.Set)
+			set := object.NewSet()
+
+			for _, elem := range lit.Elements {
+				obj := e.Evaluate(elem, env)
+				if object.IsErr(obj) {
+					return obj
+				}
+				obj = e.mapKey(obj, elem.Context())
+				if object.IsErr(obj) {
+					return obj
+				}
+				hashable, ok := obj.(object.Hashable)
+				if !ok {
+					return &object.Exception{
+						Msg: fmt.Sprintf("Cannot use type %T as element of Set", obj),
+						Con: elem.Context(),
+					}
+				}
+				set.Add(hashable)
+			}
+
+			return set
+
 		case *ast.IndexExpr:
 			idx := node.(ast.Expression).(*ast.IndexExpr)
 			return e.evalIndexExpr(idx, env)
 
+		case *ast.RangeExpr:
+			rng := node.(ast.Expression).(*ast.RangeExpr)
+			bounds := e.evalRangeBounds(rng, env)
+			if len(bounds) == 1 {
+				return bounds[0]
+			}
+			return MakeRange(bounds[0], bounds[1], bounds[2], rng.Inclusive, rng.Context())
+
+		case *ast.AssignExpr:
+			assign := node.(ast.Expression).(*ast.AssignExpr)
+			return e.evalAssignExpr(assign, env)
+
 		default:
 			return NULL
 		}
@@ -390,10 +585,6 @@ func (e *Evaluator) Evaluate(node ast.Node, env *object.Environment) object.Obje
 			Con: node.Context(),
 		}
 	}
-	return &object.Exception{
-		Msg: fmt.Sprintf("Evaluate: unreachable code, got %T", node),
-		Con: node.Context(),
-	}
 }
 
 func (e *Evaluator) evalProgram(prog *ast.Program, env *object.Environment) (object.Object, error) {
@@ -415,7 +606,7 @@ func (e *Evaluator) evalBlockStmt(stmt *ast.BlockStatement, env *object.Environm
 
 	for _, stmt := range stmt.Statements {
 		result = e.Evaluate(stmt, env)
-		if !object.IsNull(result) && result.Type() == object.RETURN || object.IsBreak(result) {
+		if !object.IsNull(result) && result.Type() == object.RETURN || object.IsBreak(result) || object.IsContinue(result) || object.IsErr(result) {
 			return result
 		}
 	}
@@ -425,6 +616,104 @@ func (e *Evaluator) evalBlockStmt(stmt *ast.BlockStatement, env *object.Environm
 	return result
 }
 
+func (e *Evaluator) evalWhileStatement(stmt *ast.WhileStatement, env *object.Environment) object.Object {
+	e.loopcount++
+	defer func() { e.loopcount-- }()
+
+	for {
+		val := e.Evaluate(stmt.Condition, env)
+		if object.IsErr(val) {
+			return val
+		}
+		if !EvaluateTruthiness(val) {
+			return NULL
+		}
+		result := e.evalBlockStmt(stmt.Body, env)
+		if res, done := loopExit(result, stmt.Label); done {
+			return res
+		}
+	}
+}
+
+func (e *Evaluator) evalForStatement(stmt *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := e.Evaluate(stmt.Iterable, env)
+	if object.IsErr(iterable) {
+		return iterable
+	}
+	iter := Iterate(iterable, len(stmt.Vars), stmt.Iterable.Context())
+	if object.IsErr(iter) {
+		return iter
+	}
+
+	e.loopcount++
+	defer func() { e.loopcount-- }()
+
+	for {
+		key, val, ok := iter.(*object.Iterator).Next()
+		if !ok {
+			return NULL
+		}
+
+		// each iteration gets a fresh binding of the loop variables
+		loopEnv := object.NewEnclosedEnv(env)
+		if len(stmt.Vars) == 2 {
+			if exc := bind(stmt.Vars[0], key, loopEnv); object.IsErr(exc) {
+				return exc
+			}
+		}
+		if exc := bind(stmt.Vars[len(stmt.Vars)-1], val, loopEnv); object.IsErr(exc) {
+			return exc
+		}
+
+		result := e.evalBlockStmt(stmt.Body, loopEnv)
+		if res, done := loopExit(result, stmt.Label); done {
+			return res
+		}
+	}
+}
+
+func (e *Evaluator) evalLoopStatement(stmt *ast.LoopStatement, env *object.Environment) object.Object {
+	e.loopcount++
+	defer func() { e.loopcount-- }()
+
+	for {
+		result := e.evalBlockStmt(stmt.Body, env)
+		if res, done := loopExit(result, stmt.Label); done {
+			return res
+		}
+	}
+}
+
+// loopExit checks the result of running the body of a loop with the given label.
+// It reports whether the loop is done, along with what the loop evaluates to.
+// A break or continue aimed at an outer loop ends the loop and is passed on.
+func loopExit(result object.Object, label *ast.Identifier) (object.Object, bool) {
+	name := ""
+	if label != nil {
+		name = label.Value
+	}
+
+	switch result := result.(type) {
+	case *object.Break:
+		if result.Label != "" && result.Label != name {
+			return result, true
+		}
+		if result.Value == nil {
+			return NULL, true
+		}
+		return result.Value, true
+	case *object.Continue:
+		if result.Label != "" && result.Label != name {
+			return result, true
+		}
+		return nil, false
+	case *object.Return, *object.Exception:
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
 func (e *Evaluator) evalExpressions(
 	exprs []ast.Expression,
 	env *object.Environment,
@@ -442,49 +731,283 @@ func (e *Evaluator) evalExpressions(
 	return objects
 }
 
+// evalArgs evaluates the arguments of a call, expanding any spread
+// arrays or tuples into separate arguments
+func (e *Evaluator) evalArgs(
+	call *ast.FunctionCall,
+	env *object.Environment,
+) ([]object.Object, map[string]object.Object, object.Object) {
+	args := []object.Object{}
+	for _, param := range call.Params {
+		spread, ok := param.(*ast.Spread)
+		if !ok {
+			arg := e.Evaluate(param, env)
+			if object.IsErr(arg) {
+				return nil, nil, arg
+			}
+			args = append(args, arg)
+			continue
+		}
+
+		switch val := e.Evaluate(spread.Value, env).(type) {
+		case *object.Array:
+			args = append(args, val.Elements...)
+		case *object.Tuple:
+			args = append(args, val.Elements...)
+		default:
+			if object.IsErr(val) {
+				return nil, nil, val
+			}
+			return nil, nil, &object.Exception{
+				Msg: fmt.Sprintf("Cannot spread type %T into arguments", val),
+				Con: spread.Context(),
+			}
+		}
+	}
+
+	kwargs := make(map[string]object.Object)
+	for _, kw := range call.Keywords {
+		arg := e.Evaluate(kw.Value, env)
+		if object.IsErr(arg) {
+			return nil, nil, arg
+		}
+		kwargs[kw.Name.Value] = arg
+	}
+
+	return args, kwargs, nil
+}
+
 func (e *Evaluator) applyFunction(
 	fn object.Object,
 	args []object.Object,
+	kwargs map[string]object.Object,
 ) object.Object {
-	function, ok := fn.(*object.Function)
-	if !ok {
-		if builtin, ok := fn.(*object.Builtin); ok {
-			return builtin.Fn(e.Ctxt, args...)
+	var function *object.Function
+	// implicit is the number of arguments not passed by the caller
+	implicit := 0
+	switch fn := fn.(type) {
+	case *object.Function:
+		function = fn
+	case *object.Builtin:
+		if len(kwargs) > 0 {
+			return &object.Exception{
+				Msg: "Builtin functions do not take keyword arguments",
+				Con: e.Ctxt,
+			}
 		}
+		return fn.Fn(e.Ctxt, args...)
+	case *object.Class:
+		return e.instantiate(fn, args, kwargs)
+	case *object.BoundMethod:
+		function = fn.Method
+		args = append([]object.Object{fn.Receiver}, args...)
+		implicit = 1
+	default:
 		return &object.Exception{
 			Msg: "Not a function",
 			Con: e.Ctxt,
 		}
 	}
 
-	len1 := len(args)
-	len2 := len(function.Params)
-	if len1 != len2 {
-		return &object.Exception{
-			Msg: fmt.Sprintf(
-				"Param mismatch: expected %d, got %d", len2, len1,
-			),
-			Con: e.Ctxt,
-		}
-	}
+	// break and continue cannot reach a loop outside the function
+	loopcount := e.loopcount
+	e.loopcount = 0
+	defer func() { e.loopcount = loopcount }()
 
-	extendedEnv := extendFunctionEnv(function, args)
+	extendedEnv, exc := e.extendFunctionEnv(function, args, kwargs, implicit)
+	if exc != nil {
+		return exc
+	}
 	evaluated := e.Evaluate(function.Body, extendedEnv)
 
 	return unwrapReturnValue(evaluated)
 }
 
-func extendFunctionEnv(
+// newClass creates a class from its declaration in env
+func newClass(decl *ast.ClassDecl, env *object.Environment) *object.Class {
+	class := &object.Class{
+		Name:    decl.Name.Value,
+		Fields:  decl.Fields,
+		Methods: make(map[string]*object.Function),
+		Env:     env,
+		Traits:  make(map[string]*object.Trait),
+	}
+	for _, method := range decl.Methods {
+		class.Methods[method.Name.Value] = &object.Function{
+			Params: method.Params,
+			Body:   method.Body,
+			Env:    env,
+		}
+	}
+	return class
+}
+
+// instantiate creates an instance of a class, giving its fields their
+// default values and then passing args to its init method, if it has one.
+func (e *Evaluator) instantiate(
+	class *object.Class,
+	args []object.Object,
+	kwargs map[string]object.Object,
+) object.Object {
+	inst := &object.Instance{Class: class, Fields: make(map[string]object.Object)}
+	for _, field := range class.Fields {
+		val := e.Evaluate(field.Value, class.Env)
+		if object.IsErr(val) {
+			return val
+		}
+		inst.Fields[field.Name.Value] = val
+	}
+
+	init, ok := class.Methods["init"]
+	if !ok {
+		if len(args)+len(kwargs) != 0 {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Param mismatch: expected 0, got %d", len(args)+len(kwargs)),
+				Con: e.Ctxt,
+			}
+		}
+		return inst
+	}
+
+	method := &object.BoundMethod{Name: "init", Receiver: inst, Method: init}
+	res := e.applyFunction(method, args, kwargs)
+	if object.IsErr(res) {
+		return res
+	}
+	return inst
+}
+
+// extendFunctionEnv binds the arguments of a call to the parameters of fn.
+// Positional arguments are bound first, then keyword arguments by name,
+// and any parameters left over take their defaults. A rest parameter
+// collects the positional arguments that no other parameter takes.
+// implicit is the number of arguments, such as the receiver of a method,
+// that the caller did not pass, which errors do not count.
+func (e *Evaluator) extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	kwargs map[string]object.Object,
+	implicit int,
+) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnv(fn.Env)
+	// evaluating defaults moves the context, so errors use that of the call
+	con := e.Ctxt
+
+	params := fn.Params
+	var rest *ast.RestPattern
+	if len(params) > 0 {
+		if last, ok := params[len(params)-1].(*ast.RestPattern); ok {
+			params, rest = params[:len(params)-1], last
+		}
+	}
+	if len(args) > len(params) && rest == nil {
+		return nil, e.arityError(fn, len(args), implicit)
+	}
 
-	for i, param := range fn.Params {
-		env.Set(param.Value, args[i])
+	// only parameters that are plain names can be passed by keyword
+	names := map[string]bool{}
+	for _, param := range params {
+		if dp, ok := param.(*ast.DefaultParam); ok {
+			param = dp.Param
+		}
+		if ident, ok := param.(*ast.Identifier); ok {
+			names[ident.Value] = true
+		}
+	}
+	keywords := []string{}
+	for name := range kwargs {
+		keywords = append(keywords, name)
+	}
+	sort.Strings(keywords)
+	for _, name := range keywords {
+		if !names[name] {
+			return nil, &object.Exception{
+				Msg: fmt.Sprintf("Unknown keyword argument %s", name),
+				Con: con,
+			}
+		}
 	}
 
-	return env
+	for i, param := range params {
+		var def ast.Expression
+		if dp, ok := param.(*ast.DefaultParam); ok {
+			param, def = dp.Param, dp.Default
+		}
+		name := ""
+		if ident, ok := param.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+		kwarg, byName := kwargs[name]
+
+		var arg object.Object
+		switch {
+		case i < len(args) && byName:
+			return nil, &object.Exception{
+				Msg: fmt.Sprintf("Got multiple values for parameter %s", param.String()),
+				Con: con,
+			}
+		case i < len(args):
+			arg = args[i]
+		case byName:
+			arg = kwarg
+		case def != nil:
+			// defaults are evaluated where the function was defined,
+			// and can refer to the parameters before them
+			if arg = e.Evaluate(def, env); object.IsErr(arg) {
+				return nil, arg
+			}
+		case len(kwargs) == 0:
+			return nil, e.arityError(fn, len(args), implicit)
+		default:
+			return nil, &object.Exception{
+				Msg: fmt.Sprintf("Missing argument for parameter %s", param.String()),
+				Con: con,
+			}
+		}
+
+		if exc := bind(param, arg, env); object.IsErr(exc) {
+			return nil, exc
+		}
+	}
+
+	if rest != nil {
+		extra := []object.Object{}
+		if len(args) > len(params) {
+			extra = append(extra, args[len(params):]...)
+		}
+		env.Set(rest.Name.Value, &object.Array{Elements: extra})
+	}
+
+	return env, nil
+}
+
+// arityError returns an exception for a call to fn that passed the wrong
+// number of positional arguments, saying how many it takes
+func (e *Evaluator) arityError(fn *object.Function, got, implicit int) object.Object {
+	required, max, variadic := -implicit, -implicit, false
+	for _, param := range fn.Params {
+		switch param.(type) {
+		case *ast.RestPattern:
+			variadic = true
+		case *ast.DefaultParam:
+			max++
+		default:
+			required++
+			max++
+		}
+	}
+
+	expected := fmt.Sprint(required)
+	switch {
+	case variadic:
+		expected = fmt.Sprintf("at least %d", required)
+	case max > required:
+		expected = fmt.Sprintf("%d to %d", required, max)
+	}
+	return &object.Exception{
+		Msg: fmt.Sprintf("Param mismatch: expected %s, got %d", expected, got-implicit),
+		Con: e.Ctxt,
+	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -495,65 +1018,226 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
+func (e *Evaluator) evalInterpStr(interp *ast.InterpStr, env *object.Environment) object.Object {
+	parts := e.evalExpressions(interp.Parts, env)
+	if len(parts) == 1 && object.IsErr(parts[0]) {
+		return parts[0]
+	}
+	return Concat(parts)
+}
+
 func (e *Evaluator) evalIndexExpr(idx *ast.IndexExpr, env *object.Environment) object.Object {
 	left := e.Evaluate(idx.Left, env)
+	if object.IsErr(left) {
+		return left
+	}
+
+	if rng, ok := idx.Index.(*ast.RangeExpr); ok {
+		bounds := e.evalRangeBounds(rng, env)
+		if len(bounds) == 1 {
+			return bounds[0]
+		}
+		return Slice(left, bounds[0], bounds[1], bounds[2], rng.Inclusive, idx.Context())
+	}
+
 	index := e.Evaluate(idx.Index, env)
+	if object.IsErr(index) {
+		return index
+	}
+	if _, ok := left.(*object.Map); ok {
+		index = e.mapKey(index, idx.Index.Context())
+		if object.IsErr(index) {
+			return index
+		}
+	}
+
+	return Index(left, index, idx.Context())
+}
+
+func (e *Evaluator) evalDotExpression(dot *ast.DotExpression, env *object.Environment) object.Object {
+	left := e.Evaluate(dot.Left, env)
+	if object.IsErr(left) {
+		return left
+	}
 
-	switch left.(type) {
-	case *object.Array:
-		left := left.(*object.Array)
-		pos, ok := index.(*object.Integer)
+	switch right := dot.Right.(type) {
+	case *ast.Identifier:
+		return member(left, right)
+
+	case *ast.FunctionCall:
+		ident, ok := right.Ident.(*ast.Identifier)
 		if !ok {
 			return &object.Exception{
-				Msg: fmt.Sprintf("Cannot index into array with index of type %T", pos),
-				Con: idx.Context(),
+				Msg: fmt.Sprintf("Cannot call %s on type %T", right.Ident.String(), left),
+				Con: right.Ident.Context(),
 			}
 		}
-		if len := len(left.Elements); int(pos.Value) > len-1 {
+		function := member(left, ident)
+		if object.IsErr(function) {
+			return function
+		}
+
+		args, kwargs, exc := e.evalArgs(right, env)
+		if exc != nil {
+			return exc
+		}
+
+		return e.applyFunction(function, args, kwargs)
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot access %s on type %T", dot.Right.String(), left),
+			Con: dot.Right.Context(),
+		}
+	}
+}
+
+// member looks up a member of a module or instance.
+// The methods of an instance are bound to it when they are looked up.
+func member(obj object.Object, ident *ast.Identifier) object.Object {
+	switch obj := obj.(type) {
+	case *object.Module:
+		return moduleMember(obj, ident)
+	case *object.Instance:
+		if field, ok := obj.Fields[ident.Value]; ok {
+			return field
+		}
+		if method, ok := obj.Class.Methods[ident.Value]; ok {
+			return &object.BoundMethod{Name: ident.Value, Receiver: obj, Method: method}
+		}
+		return &object.Exception{
+			Msg: fmt.Sprintf("%s has no member %s", obj.Class.Name, ident.Value),
+			Con: ident.Context(),
+		}
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot access members of type %T", obj),
+			Con: ident.Context(),
+		}
+	}
+}
+
+// evalRangeBounds evaluates the start, end and step of a range,
+// any of which are NULL if left out. If one of them fails,
+// only the exception is returned.
+func (e *Evaluator) evalRangeBounds(rng *ast.RangeExpr, env *object.Environment) []object.Object {
+	bounds := []object.Object{}
+	for _, expr := range []ast.Expression{rng.Start, rng.End, rng.Step} {
+		if expr == nil {
+			bounds = append(bounds, NULL)
+			continue
+		}
+		bound := e.Evaluate(expr, env)
+		if object.IsErr(bound) {
+			return []object.Object{bound}
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds
+}
+
+func (e *Evaluator) evalAssignExpr(assign *ast.AssignExpr, env *object.Environment) object.Object {
+	switch target := assign.Target.(type) {
+	case *ast.Identifier:
+		val := e.Evaluate(assign.Value, env)
+		if object.IsErr(val) {
+			return val
+		}
+		if assign.Operator != "" {
+			current, ok := env.Get(target.Value)
+			if !ok {
+				return &object.Exception{
+					Msg: fmt.Sprintf("Cannot assign to undeclared variable %s", target.Value),
+					Con: target.Context(),
+				}
+			}
+			val = e.operate(current, val, assign.Operator, assign.Context())
+			if object.IsErr(val) {
+				return val
+			}
+		}
+		if !env.Assign(target.Value, val) {
 			return &object.Exception{
-				Msg: fmt.Sprintf("Cannot get index %d of array of length %d", pos.Value, len),
-				Con: idx.Context(),
+				Msg: fmt.Sprintf("Cannot assign to undeclared variable %s", target.Value),
+				Con: target.Context(),
 			}
 		}
-		return left.Elements[int(pos.Value)]
+		return val
 
-	case *object.String:
-		left := left.(*object.String)
-		pos, ok := index.(*object.Integer)
+	case *ast.IndexExpr:
+		left := e.Evaluate(target.Left, env)
+		if object.IsErr(left) {
+			return left
+		}
+		index := e.Evaluate(target.Index, env)
+		if object.IsErr(index) {
+			return index
+		}
+		if _, ok := left.(*object.Map); ok {
+			index = e.mapKey(index, target.Index.Context())
+			if object.IsErr(index) {
+				return index
+			}
+		}
+		val := e.Evaluate(assign.Value, env)
+		if object.IsErr(val) {
+			return val
+		}
+		if assign.Operator != "" {
+			current := Index(left, index, target.Context())
+			if object.IsErr(current) {
+				return current
+			}
+			val = e.operate(current, val, assign.Operator, assign.Context())
+			if object.IsErr(val) {
+				return val
+			}
+		}
+		return SetIndex(left, index, val, target.Context())
+
+	case *ast.DotExpression:
+		left := e.Evaluate(target.Left, env)
+		if object.IsErr(left) {
+			return left
+		}
+		inst, ok := left.(*object.Instance)
 		if !ok {
 			return &object.Exception{
-				Msg: fmt.Sprintf("Cannot index into string with index of type %T", pos),
-				Con: idx.Context(),
+				Msg: fmt.Sprintf("Cannot assign to members of type %T", left),
+				Con: target.Context(),
 			}
 		}
-		if len := len(left.Value); int(pos.Value) > len-1 {
+		name, ok := target.Right.(*ast.Identifier)
+		if !ok {
 			return &object.Exception{
-				Msg: fmt.Sprintf("Cannot get index %d of array of length %d", pos.Value, len),
-				Con: idx.Context(),
+				Msg: fmt.Sprintf("Cannot assign to %s", assign.Target.String()),
+				Con: target.Context(),
 			}
 		}
-		return &object.String{Value: string(left.Value[int(pos.Value)])}
-
-	case *object.Map:
-		left := left.(*object.Map)
-
-		hashable, ok := index.(object.Hashable)
+		current, ok := inst.Fields[name.Value]
 		if !ok {
 			return &object.Exception{
-				Msg: fmt.Sprintf("Cannot use type %T as key for Map", index),
-				Con: idx.Index.Context(),
+				Msg: fmt.Sprintf("%s has no field %s", inst.Class.Name, name.Value),
+				Con: name.Context(),
 			}
 		}
-		ret, ok := left.Elements[hashable.HashKey()]
-		if !ok {
-			return NULL
+		val := e.Evaluate(assign.Value, env)
+		if object.IsErr(val) {
+			return val
+		}
+		if assign.Operator != "" {
+			val = e.operate(current, val, assign.Operator, assign.Context())
+			if object.IsErr(val) {
+				return val
+			}
 		}
-		return ret
+		inst.Fields[name.Value] = val
+		return val
 
 	default:
 		return &object.Exception{
-			Msg: fmt.Sprintf("Cannot use type %T as index", left),
-			Con: idx.Context(),
+			Msg: fmt.Sprintf("Cannot assign to %s", assign.Target.String()),
+			Con: assign.Target.Context(),
 		}
 	}
 }
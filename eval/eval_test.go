@@ -1,6 +1,9 @@
 package eval
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/cartoon-raccoon/lemur/lexer"
@@ -34,6 +37,51 @@ func TestExprStmtEval(t *testing.T) {
 			&object.Float{Value: 428.09},
 			"428.090000",
 		},
+		{
+			"0x10 + 0b1 + 0o7 + 1_000",
+			&object.Integer{Value: 1024},
+			"1024",
+		},
+		{
+			"1.5e2 - 2.5E-1",
+			&object.Float{Value: 149.75},
+			"149.750000",
+		},
+		{
+			`"foo" + "bar"`,
+			&object.String{Value: "foobar"},
+			"foobar",
+		},
+		{
+			`let x = 3; "x is ${x}, twice is ${x * 2}, ${"a${true}"}"`,
+			&object.String{Value: "x is 3, twice is 6, atrue"},
+			"x is 3, twice is 6, atrue",
+		},
+		{
+			"`raw ${x} \\n\nline`",
+			&object.String{Value: "raw ${x} \\n\nline"},
+			"raw ${x} \\n\nline",
+		},
+		{
+			"let x = 1; x = x + 4; x *= 2; x",
+			&object.Integer{Value: 10},
+			"5\n10\n10",
+		},
+		{
+			"let a = 1; let b = 2; a = b = 7; a + b",
+			&object.Integer{Value: 14},
+			"7\n14",
+		},
+		{
+			`let arr = [1, 2, 3]; let m = {"k": 5}; arr[1] += 10; m["k"] -= 1; m["new"] = arr[1]; arr[0] = m["k"] + m["new"]; arr`,
+			&object.Array{},
+			"12\n4\n12\n16\n[16, 12, 3]",
+		},
+		{
+			"let s = 0; let f = fn() { s += 1; }; f(); f(); s",
+			&object.Integer{Value: 2},
+			"1\n2\n2",
+		},
 	}
 
 	for i, test := range tests {
@@ -67,6 +115,726 @@ func TestExprStmtEval(t *testing.T) {
 	}
 }
 
+func TestAssignmentErrors(t *testing.T) {
+	tests := []struct {
+		Input string
+		Error string
+	}{
+		{"y = 1", "Cannot assign to undeclared variable y"},
+		{"y += 1", "Cannot assign to undeclared variable y"},
+		{"let f = fn() { let z = 1; }; f(); z = 2", "Cannot assign to undeclared variable z"},
+		{"let a = [1]; a[3] = 2", "Cannot set index 3 of array of length 1"},
+		{`let s = "abc"; s[0] = "x"`, "Cannot assign to an index of a string, strings are immutable"},
+	}
+
+	for _, test := range tests {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestForLoopEval(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"let sum = 0; for x in [1, 2, 3] { sum += x; } sum", "6"},
+		{"let sum = 0; for i, x in [10, 20, 30] { sum += i * x; } sum", "80"},
+		{`let s = ""; for c in "héllo" { s = c + s; } s`, "olléh"},
+		{`let s = ""; for i, c in "ab" { s = s + "${i}${c}"; } s`, "0a1b"},
+		{`let s = ""; for k in {"a": 1, "b": 2} { s = s + k; } s`, "ab"},
+		{`let s = 0; for k, v in {"a": 1, "b": 2} { s += v; } s`, "3"},
+		{"let sum = 0; for i in range(5) { sum += i; } sum", "10"},
+		{"let a = []; for i in range(10, 0, -3) { push(a, i); } a", "[10, 7, 4, 1]"},
+		{"let a = []; for i in range(3, 3) { push(a, i); } a", "[]"},
+		{"let sum = 0; for x in [1, 2, 3, 4] { if (x == 3) { break; }; sum += x; } sum", "3"},
+		{"let a = [1, 2]; for x in a { push(a, x); } a", "[1, 2, 1, 2]"},
+		{"let fs = []; for x in [1, 2] { push(fs, fn() { x }); } fs[0]() + fs[1]()", "3"},
+		{"let x = 7; for x in [1, 2] { x; } x", "7"},
+		{"let f = fn() { for x in range(1, 10) { if (x > 2) { return x; } } }; f()", "3"},
+		{"let f = fn() { let i = 0; while (true) { return i; } }; f()", "0"},
+		{`len(range(0, 10, 3)) + len({"a": 1})`, "5"},
+		{"let i = 0; loop { i += 1; if (i == 10) { break; } } i", "10"},
+		{"let i = 0; let s = 0; while (i < 5) { i += 1; if (i == 2) { continue; }; s += i; } s", "13"},
+		{"let s = 0; for x in range(10) { if (x > 3) { continue; }; s += x; } s", "6"},
+		{"let s = 0; loop { s += 1; for x in [1, 2] { if (x == 2) { break; }; s += 10; } if (s > 30) { break; } } s", "33"},
+		{"let f = fn() { let i = 0; loop { i += 1; if (i > 4) { return i; } } }; f()", "5"},
+		{"let i = 0; let x = loop { i += 1; if (i == 3) { break i * 10; } }; x", "30"},
+		{"let x = while (false) { 1; }; x", "Null"},
+		{"let x = for c in \"abc\" { if (c == \"b\") { break c; } }; x", "b"},
+		{"let n = 0; outer: for a in range(3) { for b in range(3) { if (b == 1) { continue outer; }; if (a == 2) { break outer; }; n += 1; } } n", "2"},
+		{"let n = 0; outer: loop { inner: while (true) { n += 1; if (n == 3) { break outer; }; continue inner; } } n", "3"},
+		{"outer: loop { for x in [1, 2, 3] { if (x == 2) { break outer x * 100; } } }", "200"},
+		{"let v = 9; let x = loop { break v; }; x", "9"},
+	}
+
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+}
+
+func TestForLoopErrors(t *testing.T) {
+	tests := []struct {
+		Input string
+		Error string
+	}{
+		{"for x in 5 { x; }", "Cannot iterate over type *object.Integer"},
+		{"for x in range(1, 2, 0) { x; }", "Step of range() cannot be zero"},
+		{`for x in range("a") { x; }`, "Cannot use type *object.String as argument for range()"},
+		{"for x in [1] { y = x; }", "Cannot assign to undeclared variable y"},
+		{"continue", "Cannot use continue outside of loop"},
+		{"let f = fn() { break; }; loop { f(); }", "Cannot use break outside of loop"},
+		{"let f = fn() { continue; }; for x in [1] { f(); }", "Cannot use continue outside of loop"},
+	}
+
+	for _, test := range tests {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+// lastResult evaluates a program with e and returns the result of its last statement
+func lastResult(e *Evaluator, input string) (object.Object, error) {
+	p, err := parser.New(lexer.New(input))
+	if err != nil {
+		return nil, err
+	}
+	prog := p.Parse()
+	if errs := p.CheckErrors(); errs != nil {
+		return nil, fmt.Errorf("Errors while parsing: %v", errs)
+	}
+	res, ok := e.Evaluate(prog, object.NewEnv()).(*object.StmtResults)
+	if !ok {
+		return nil, fmt.Errorf("Expected program results")
+	}
+	return res.Results[len(res.Results)-1], nil
+}
+
+// testEvalError evaluates a program with e and checks that
+// its last statement raises an exception with the message want
+func testEvalError(t *testing.T, e *Evaluator, input string, want string) {
+	t.Helper()
+
+	res, err := lastResult(e, input)
+	if err != nil {
+		t.Errorf("%q: %s", input, err)
+		return
+	}
+	exc, ok := res.(*object.Exception)
+	if !ok {
+		t.Errorf("%q: Expected exception, got %s", input, res.Inspect())
+		return
+	}
+	if exc.Msg != want {
+		t.Errorf("%q: Expected %q, got %q", input, want, exc.Msg)
+	}
+}
+
+func TestRangesAndSlices(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"let s = 0; for i in [1..5] { s += i; } s", "10"},
+		{"let s = 0; for i in [1..=5] { s += i; } s", "15"},
+		{"let a = []; for i in [10..0:-3] { push(a, i); } a", "[10, 7, 4, 1]"},
+		{"let a = []; for i in [10..=1:-3] { push(a, i); } a", "[10, 7, 4, 1]"},
+		{"let n = 4; len([..n * 2:3])", "3"},
+		{"len([5..1])", "0"},
+		{"[1, 2, 3, 4, 5][1..3]", "[2, 3]"},
+		{"[1, 2, 3, 4, 5][1..=3]", "[2, 3, 4]"},
+		{"[1, 2, 3, 4, 5][..2]", "[1, 2]"},
+		{"[1, 2, 3, 4, 5][3..]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][..:2]", "[1, 3, 5]"},
+		{"[1, 2, 3][1..100]", "[2, 3]"},
+		{"[1, 2, 3][5..]", "[]"},
+		{`"lemurs"[..5]`, "lemur"},
+		{`"héllo"[1..3]`, "él"},
+		{`"héllo"[1]`, "é"},
+		{`len("héllo")`, "5"},
+	}
+
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"[0..10:0]", "Step of range cannot be zero"},
+		{`[0.."a"]`, "Cannot use type *object.String as end of range"},
+		{"[1, 2][..:-1]", "Step of slice must be positive"},
+		{"5[1..2]", "Cannot slice type *object.Integer"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestNegativeIndices(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"[1, 2, 3][-1]", "3"},
+		{"[1, 2, 3][-3]", "1"},
+		{`"héllo"[-4]`, "é"},
+		{"let a = [1, 2, 3]; a[-1] = 9; a", "[1, 2, 9]"},
+		{"let a = [1, 2, 3]; a[-2] += 5; a", "[1, 7, 3]"},
+		{"[1, 2, 3, 4, 5][-2..]", "[4, 5]"},
+		{"[1, 2, 3, 4, 5][1..-1]", "[2, 3, 4]"},
+		{"[1, 2, 3, 4, 5][..=-2]", "[1, 2, 3, 4]"},
+		{"[1, 2, 3][-10..]", "[1, 2, 3]"},
+		{`"lemurs"[-6..-1]`, "lemur"},
+	}
+
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"[1, 2, 3][-4]", "Cannot get index -4 of array of length 3"},
+		{"[1, 2, 3][3]", "Cannot get index 3 of array of length 3"},
+		{`"abc"[-4]`, "Cannot get index -4 of string of length 3"},
+		{"[][-1]", "Cannot get index -1 of array of length 0"},
+		{"let a = [1]; a[-2] = 2", "Cannot set index -2 of array of length 1"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestModules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"util.lm":      "let scale = 10; fn area(w, h) { double(w) * h } fn double(x) { x * 2 }",
+		"counter.lm":   "let count = [0]; fn bump() { count[0] += 1; count[0] }",
+		"user.lm":      "import counter; let first = counter.bump();",
+		"a.lm":         "import b;",
+		"b.lm":         "import a;",
+		"broken.lm":    "let x = [1][5];",
+		"warned.lm":    "let x = match 1 { 1 => 2 };",
+		"lib/extra.lm": `let greeting = "hi";`,
+		"main.lm":      "import (util, extra); let result = util.area(2, 3) + util.scale;",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// each test gets a fresh loader, so modules are evaluated again
+	newEvaluator := func() *Evaluator {
+		e := New()
+		e.loader = NewLoader(filepath.Join(dir, "lib"))
+		e.dir = dir
+		return e
+	}
+
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"import util; util.area(2, 3)", "12"},
+		{"import util; util.scale", "10"},
+		{"import util; let f = util.double; f(4)", "8"},
+		{"import extra; extra.greeting", "hi"},
+		{"import (counter, user); user.first; counter.bump()", "2"},
+		{"import (user, counter); counter.bump() + user.first", "3"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(newEvaluator(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"import nothere", "Could not find module nothere"},
+		{"import a", "Import cycle: a -> b -> a"},
+		{"import util; util.nope", "Module util has no member nope"},
+		{"import util; util.nope(1)", "Module util has no member nope"},
+		{"let x = 1; x.y", "Cannot access members of type *object.Integer"},
+		{"import broken", "Cannot get index 5 of array of length 1"},
+	}
+	for _, test := range errors {
+		testEvalError(t, newEvaluator(), test.Input, test.Error)
+	}
+
+	e := New()
+	e.loader = NewLoader(filepath.Join(dir, "lib"))
+	env := object.NewEnv()
+	if res := e.EvaluateFile(filepath.Join(dir, "main.lm"), env); object.IsErr(res) {
+		t.Fatalf("Error evaluating main.lm: %s", res.Inspect())
+	}
+	if result, ok := env.Get("result"); !ok || result.Inspect() != "22" {
+		t.Errorf("Expected result to be 22, got %v", result)
+	}
+
+	// warnings from parsing a module reach the file that imported it
+	if err := os.WriteFile(filepath.Join(dir, "main.lm"), []byte("import warned;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e = New()
+	if res := e.EvaluateFile(filepath.Join(dir, "main.lm"), object.NewEnv()); object.IsErr(res) {
+		t.Fatalf("Error evaluating main.lm: %s", res.Inspect())
+	}
+	warnings := e.Warnings()
+	want := filepath.Join(dir, "warned.lm") + ": Match is not exhaustive, add a `_` arm to handle any other value: line 1, col 9"
+	if len(warnings) != 1 || warnings[0].Error() != want {
+		t.Errorf("Expected warning %q, got %v", want, warnings)
+	}
+}
+
+func TestClasses(t *testing.T) {
+	class := `class Point {
+	let x = 0;
+	let y = 0;
+	fn init(self, x, y) { self.x = x; self.y = y; }
+	fn norm(self) { self.x * self.x + self.y * self.y }
+	fn shift(self, by) { self.x += by; self.y += by; self }
+}
+class Counter {
+	let count = 0;
+	let history = [];
+	fn bump(self) { self.count += 1; push(self.history, self.count); self.count }
+	fn last(self) { self.history[-1] }
+}
+`
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"let p = Point(3, 4); p.norm()", "25"},
+		{"let p = Point(3, 4); p.x = 10; p.x + p.y", "14"},
+		{"let p = Point(1, 1); p.y *= 7; p", "Point{x: 1, y: 7}"},
+		{"Point(1, 2).shift(2).shift(1)", "Point{x: 4, y: 5}"},
+		{"let p = Point(1, 2); let f = p.norm; p.x = 2; f()", "8"},
+		{"let c = Counter(); c.bump(); c.bump()", "2"},
+		{"let a = Counter(); a.bump(); let b = Counter(); b.bump(); b.history", "[1]"},
+		{"let p = Point(0, 0); p.norm", "method Point.norm"},
+		{"Point", "class Point"},
+		{"let ps = [Point(1, 0), Point(0, 2)]; ps[1].y", "2"},
+		{"let c = make(); c.count fn make() { Counter() }", "0"},
+		{"let c = Counter(); c.bump(); c.bump(); c.last()", "2"},
+		{"let c = Counter(); c.bump(); c.history[0] = 5; c.history", "[5]"},
+		{"let c = Counter(); c.bump(); c.history[0] += 2; c.last()", "3"},
+		{"let c = Counter(); c.bump(); c.history[0..1]", "[1]"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), class+test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"Point(1)", "Param mismatch: expected 2, got 1"},
+		{"Counter(1)", "Param mismatch: expected 0, got 1"},
+		{"Point(1, 2).z", "Point has no member z"},
+		{"Point(1, 2).scale(2)", "Point has no member scale"},
+		{"let p = Point(1, 2); p.z = 1", "Point has no field z"},
+		{"let p = Point(1, 2); p.norm(5)", "Param mismatch: expected 0, got 1"},
+		{"let x = [1]; x.y = 2", "Cannot assign to members of type *object.Array"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), class+test.Input, test.Error)
+	}
+}
+
+func TestTraits(t *testing.T) {
+	decls := `class Vec {
+	let x = 0;
+	let y = 0;
+	fn init(self, x, y) { self.x = x; self.y = y; }
+}
+impl Add for Vec {
+	fn add(self, other) { Vec(self.x + other.x, self.y + other.y) }
+}
+impl Eq for Vec {
+	fn eq(self, other) { if (self.x == other.x) { self.y == other.y } else { false } }
+}
+impl Ord for Vec {
+	fn cmp(self, other) { (self.x * self.x + self.y * self.y) - (other.x * other.x + other.y * other.y) }
+}
+impl Hash for Vec {
+	fn hash(self) { "${self.x},${self.y}" }
+}
+trait Shape {
+	fn area(self);
+}
+class Square {
+	let side = 1;
+}
+impl Shape for Square {
+	fn area(self) { self.side * self.side }
+}
+class Plain {}
+`
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"Vec(1, 2) + Vec(3, 4)", "Vec{x: 4, y: 6}"},
+		{"let v = Vec(1, 1); v += Vec(1, 0); v", "Vec{x: 2, y: 1}"},
+		{"Vec(1, 2) == Vec(1, 2)", "true"},
+		{"Vec(1, 2) != Vec(1, 2)", "false"},
+		{"Vec(1, 2) < Vec(2, 2)", "true"},
+		{"Vec(3, 0) >= Vec(0, 3)", "true"},
+		{"Vec(3, 0) > Vec(0, 3)", "false"},
+		{"let m = {Vec(1, 2): \"a\"}; m[Vec(1, 2)]", "a"},
+		{"let m = {}; m[Vec(0, 1)] = 5; m[Vec(0, 1)] += 1; m", "{Vec{x: 0, y: 1}: 6}"},
+		{"let m = {Vec(1, 2): 1}; let k = 0; for key in m { k = key; } k.y", "2"},
+		{"Square().area()", "1"},
+		{"let p = Plain(); p == p", "true"},
+		{"Plain() == Plain()", "false"},
+		{"Shape", "trait Shape"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), decls+test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{decls + "Vec(1, 2) - Vec(1, 2)", "Cannot use operator `-` on Vec"},
+		{decls + "Plain() < Plain()", "Cannot use operator `<` on Plain"},
+		{decls + "{Plain(): 1}", "Cannot use Plain as key for Map, as it does not implement Hash"},
+		{"class A {} impl Eq for A { fn eq(self, other) { 1 } } impl Eq for A { fn eq(self, other) { 1 } }", "A already implements Eq"},
+		{"class A {} impl Add for A {} 1", "Impl of Add for A is missing add"},
+		{"class A {} impl Add for A { fn add(self) { 1 } }", "Method add of trait Add takes 2 parameters, got 1"},
+		{"class A {} impl Add for A { fn add(self, o) { 1 } fn sub(self, o) { 1 } }", "sub is not a method of trait Add"},
+		{"class A { fn add(self, o) { 1 } } impl Add for A { fn add(self, o) { 1 } }", "A already has a member add"},
+		{"impl Nope for A {}", "Unknown trait Nope"},
+		{"impl Add for Nope {}", "Unknown class Nope"},
+		{"let x = 1; fn f() {} impl f for A {}", "f is not a trait"},
+		{"trait T { fn t(self); } fn A() {} impl T for A {}", "A is not a class"},
+		{"class A {} impl Ord for A { fn cmp(self, o) { true } } A() < A()", "cmp of A must return an integer, got *object.Boolean"},
+		{"class A {} impl Hash for A { fn hash(self) { [1] } } {A(): 1}", "hash of A must return a hashable value, got *object.Array"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestTuples(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"(1, \"a\", true)", "(1, a, true)"},
+		{"(1,)", "(1,)"},
+		{"()", "()"},
+		{"let t = (1, 2, 3); t[0] + t[-1]", "4"},
+		{"len((1, 2))", "2"},
+		{"(1, 2, 3, 4)[1..3]", "(2, 3)"},
+		{"let (a, b) = (1, 2); a * 10 + b", "12"},
+		{"let (q, (r, s)) = (1, (2, 3)); q + r + s", "6"},
+		{"let divmod = fn(a, b) { (a / b, a - a / b * b) }; let (q, r) = divmod(17, 5); [q, r]", "[3, 2]"},
+		{"let s = 0; for x in (1, 2, 3) { s += x; } s", "6"},
+		{"let m = {(1, 2): \"a\"}; m[(1, 2)]", "a"},
+		{"let m = {}; m[(0, \"x\")] = 1; m[(0, \"x\")] += 1; m", "{(0, x): 2}"},
+		{"let m = {(1, 2): 3}; m[(2, 1)]", "Null"},
+		{"let m = {(1, (2, 3)): 4}; m[(1, (2, 3))]", "4"},
+		{"class P { let x = 1; } impl Hash for P { fn hash(self) { (self.x, 2) } } let m = {P(): 3}; m[P()]", "3"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"let t = (1, 2); t[0] = 5", "Cannot assign to an index of a tuple, tuples are immutable"},
+		{"(1, 2)[2]", "Cannot get index 2 of tuple of length 2"},
+		{"let (a, b) = (1, 2, 3);", "Expected tuple of length 2, got 3"},
+		{"let (a, b) = [1, 2];", "Cannot destructure type *object.Array as a tuple"},
+		{"let (a, (b, c)) = (1, 2);", "Cannot destructure type *object.Integer as a tuple"},
+		{"{(1, [2]): 3}", "Cannot use type *object.Array as key for Map"},
+		{"(1, [2]) in {(1, 2)}", "Cannot use type *object.Array as key for Map"},
+		{"len(set([(1, [2]), (1, [3])]))", "Cannot use type *object.Array as key for Map"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", "12"},
+		{"let [head, ...tail] = [1, 2, 3]; tail", "[2, 3]"},
+		{"let [head, ...tail] = [1]; tail", "[]"},
+		{"let [first, ...mid, last] = [1, 2, 3, 4]; [first, mid, last]", "[1, [2, 3], 4]"},
+		{"let (a, ...b) = (1, 2, 3); b", "(2, 3)"},
+		{"let {name, age} = {\"name\": \"Kim\", \"age\": 30}; \"${name} ${age}\"", "Kim 30"},
+		{"let {pos: (x, y)} = {\"pos\": (3, 4)}; x + y", "7"},
+		{"class P { let x = 1; let y = 2; } let {x, y: z} = P(); x + z", "3"},
+		{"let [{id}, (a, [b, ...c])] = [{\"id\": 7}, (1, [2, 3])]; [id, a, b, c]", "[7, 1, 2, [3]]"},
+		{"let add = fn([a, b]) { a + b }; add([3, 4])", "7"},
+		{"fn area({w, h}) { w * h } area({\"w\": 2, \"h\": 5})", "10"},
+		{"let s = 0; for [a, b] in [[1, 2], [3, 4]] { s += a * b; } s", "14"},
+		{"let s = 0; for k, (a, b) in {1: (2, 3)} { s += k + a + b; } s", "6"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"let [a, b] = [1];", "Expected array of length 2, got 1"},
+		{"let [a, b, ...c] = [1];", "Expected array of at least length 2, got 1"},
+		{"let (a, b, ...c) = (1,);", "Expected tuple of at least length 2, got 1"},
+		{"let [a] = (1,);", "Cannot destructure type *object.Tuple as an array"},
+		{"let {a} = {\"b\": 1};", "Map has no key \"a\""},
+		{"let {a} = [1];", "Cannot destructure type *object.Array as a map"},
+		{"class P { let x = 1; } let {y} = P();", "P has no field y"},
+		{"let f = fn([a, b]) { a }; f(5)", "Cannot destructure type *object.Integer as an array"},
+		{"for [a, b] in [[1, 2], [3]] { a }", "Expected array of length 2, got 1"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"match 2 { 1 => \"one\", 2 => \"two\", _ => \"many\" }", "two"},
+		{"match 7 { 1 => \"one\", _ => \"many\" }", "many"},
+		{"match -3 { -3 => true, _ => false }", "true"},
+		{"match \"hi\" { \"hi\" => 1, _ => 2 }", "1"},
+		{"match 1.0 { 1 => \"int\", 1.0 => \"float\", _ => \"other\" }", "float"},
+		{"match false { true => 1, false => 0 }", "0"},
+		{"match 5 { 0..5 => \"low\", 5..=9 => \"mid\", _ => \"high\" }", "mid"},
+		{"match 10 { 0..5 => \"low\", 5..=9 => \"mid\", _ => \"high\" }", "high"},
+		{"match [1, 2, 3] { [] => 0, [x] => x, [x, ...rest] => len(rest) }", "2"},
+		{"match [4] { [] => 0, [x] => x, [x, ...rest] => len(rest) }", "4"},
+		{"match {\"kind\": \"circle\", \"r\": 2} { {kind: \"square\", side} => side, {kind: \"circle\", r} => r * 3 }", "6"},
+		{"match (1, (2, 3)) { (a, (b, c)) => a + b + c }", "6"},
+		{"class P { let x = 0; let y = 0; } match P() { P{x: 1} => \"one\", P{x, y} => x + y }", "0"},
+		{"class A {} class B {} match B() { A{} => \"a\", B{} => \"b\" }", "b"},
+		{"match 15 { n if n > 20 => \"big\", n => \"small\" }", "small"},
+		{"match 4 { n if n > 3 => { let m = n * 2; m } _ => 0 }", "8"},
+		{"match 3 { 1 => \"one\" }", "Null"},
+		{"let n = 5; match 1 { n => n }; n", "5"},
+		{"match [1, 2] { [a, b] if a > b => \"desc\", [a, b] => \"asc\" }", "asc"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"match 1 { Q{} => 1, _ => 2 }", "Unknown class Q"},
+		{"let Q = 1; match 1 { Q{} => 1, _ => 2 }", "Q is not a class"},
+		{"match 1 { n if m => 1, _ => 2 }", "Could not find symbol m"},
+		{"class P { let x = 1; } let P{x: [a]} = P();", "Cannot destructure type *object.Integer as an array"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"fn f(a, b = 2) { a + b } f(1)", "3"},
+		{"fn f(a, b = 2) { a + b } f(1, 5)", "6"},
+		{"let f = fn(a, b = a * 2) { a + b }; f(3)", "9"},
+		{"let n = 10; let f = fn(a = n) { a }; n = 20; f()", "20"},
+		{"fn f(a, ...rest) { rest } f(1, 2, 3)", "[2, 3]"},
+		{"fn f(a, ...rest) { rest } f(1)", "[]"},
+		{"let f = fn(...xs) { len(xs) }; f(1, 2, 3, 4)", "4"},
+		{"fn f(a, b, c) { [a, b, c] } let xs = [2, 3]; f(1, ...xs)", "[1, 2, 3]"},
+		{"fn f(a, b, c) { [a, b, c] } f(...(1, 2), 3)", "[1, 2, 3]"},
+		{"fn f(...xs) { xs } f(...[], ...[1], 2)", "[1, 2]"},
+		{"fn f(a, b = 2, c = 3) { [a, b, c] } f(1, c: 9)", "[1, 2, 9]"},
+		{"fn f(a, b) { a - b } f(b: 1, a: 5)", "4"},
+		{"let f = fn(a, [b, c] = [2, 3]) { a + b + c }; f(1)", "6"},
+		{"fn f(a, b = 2, ...rest) { [a, b, rest] } f(1, 5, 6, 7)", "[1, 5, [6, 7]]"},
+		{"class P { let x = 0; fn init(self, x = 1) { self.x = x; } } P(x: 4).x", "4"},
+		{"class P { let x = 0; fn init(self, x = 1) { self.x = x; } } P().x", "1"},
+		{"class P { fn sum(self, ...xs) { len(xs) } } P().sum(1, 2)", "2"},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"fn f(a, b) { a } f(1)", "Param mismatch: expected 2, got 1"},
+		{"fn f(a, b = 2) { a } f(1, 2, 3)", "Param mismatch: expected 1 to 2, got 3"},
+		{"fn f(a, b = 2) { a } f()", "Param mismatch: expected 1 to 2, got 0"},
+		{"fn f(a, ...b) { a } f()", "Param mismatch: expected at least 1, got 0"},
+		{"class P { fn m(self, a) { a } } P().m()", "Param mismatch: expected 1, got 0"},
+		{"fn f(a, b) { a } f(1, a: 2)", "Got multiple values for parameter a"},
+		{"fn f(a, b) { a } f(b: 2)", "Missing argument for parameter a"},
+		{"fn f(a) { a } f(1, c: 2)", "Unknown keyword argument c"},
+		{"fn f(a, ...b) { a } f(1, b: 2)", "Unknown keyword argument b"},
+		{"fn f([a]) { a } f(a: [1])", "Unknown keyword argument a"},
+		{"fn f(a) { a } f(...5)", "Cannot spread type *object.Integer into arguments"},
+		{"fn f(a = m) { a } f()", "Could not find symbol m"},
+		{"len(x: 1)", "Builtin functions do not take keyword arguments"},
+		{"class P {} P(x: 1)", "Param mismatch: expected 0, got 1"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{"let s = {1, 2, 2, 3}; s", "{1, 2, 3}"},
+		{"let s = {1, 2}; len(s)", "2"},
+		{"set()", "set()"},
+		{"set([3, 1, 3])", "{3, 1}"},
+		{"set(\"abca\")", "{a, b, c}"},
+		{"set({1: 2, 3: 4})", "{1, 3}"},
+		{"2 in {1, 2}", "true"},
+		{"5 in {1, 2}", "false"},
+		{"(1, 2) in {(1, 2), (3, 4)}", "true"},
+		{"\"a\" in {\"a\": 1}", "true"},
+		{"\"mur\" in \"lemur\"", "true"},
+		{"let a = {1, 2, 3}; let b = {2, 3, 4}; a | b", "{1, 2, 3, 4}"},
+		{"let a = {1, 2, 3}; let b = {2, 3, 4}; a & b", "{2, 3}"},
+		{"let a = {1, 2, 3}; let b = {2, 3, 4}; a - b", "{1}"},
+		{"let a = {1, 2}; a - a", "set()"},
+		{"let a = {1}; a |= {2}; a", "{1, 2}"},
+		{"let s = 0; for x in {1, 2, 3} { s += x; } s", "6"},
+		{"let s = 0; for i, x in {5, 6} { s += i; } s", "1"},
+		{"len(set([(1, 2), (1, 2), (3, 4)]))", "2"},
+		{"(3, 4) in set([(1, 2), (3, 4)])", "true"},
+		{
+			"class P { let x = 0; fn init(self, x) { self.x = x; } } impl Hash for P { fn hash(self) { self.x } } len(set([P(1), P(2), P(1)]))",
+			"2",
+		},
+	}
+	for i, test := range tests {
+		res, err := lastResult(New(), test.Input)
+		if err != nil {
+			t.Errorf("Test %d: %s", i, err)
+			continue
+		}
+		if res.Inspect() != test.Expected {
+			t.Errorf("Test %d: Expected %s, got %s", i, test.Expected, res.Inspect())
+		}
+	}
+
+	errors := []struct {
+		Input string
+		Error string
+	}{
+		{"let s = {1, [2]};", "Cannot use type *object.Array as element of Set"},
+		{"[1] in {1, 2}", "Cannot use type *object.Array as element of Set"},
+		{"1 in [1, 2]", "Cannot use operator `in` on type *object.Array"},
+		{"1 in \"abc\"", "Cannot search for type *object.Integer in STR"},
+		{"{1, 2} * {3}", "Cannot use operator `*` on SET"},
+		{"{1, 2} | [3]", "Cannot operate on SET and *object.Array"},
+		{"set(1, 2)", "Expected 0 or 1 arguments for call to set(), got 2"},
+		{"set([[1]])", "Cannot use type *object.Array as element of Set"},
+		{"set([(1, [2])])", "Cannot use type *object.Array as key for Map"},
+		{"class P {} set([P()])", "Cannot use P as key for Map, as it does not implement Hash"},
+	}
+	for _, test := range errors {
+		testEvalError(t, New(), test.Input, test.Error)
+	}
+}
+
 func TestIfExprEval(t *testing.T) {
 	input := "if (6 < 7) { return 5; } else { return \"hello\"; }"
 	expected := "5"
@@ -2,6 +2,7 @@ package eval
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/cartoon-raccoon/lemur/ast"
 	"github.com/cartoon-raccoon/lemur/lexer"
@@ -24,7 +25,7 @@ func (e *Evaluator) evalPrefixExpr(expr *ast.PrefixExpr, env *object.Environment
 func (e *Evaluator) evalBangPExpr(expr ast.Expression, env *object.Environment) object.Object {
 	pexpr := e.Evaluate(expr, env)
 
-	truth := evaluateTruthiness(pexpr)
+	truth := EvaluateTruthiness(pexpr)
 	return nativeBooltoObj(!truth)
 }
 
@@ -73,10 +74,7 @@ func (e *Evaluator) evalInfixExpr(expr *ast.InfixExpr, env *object.Environment)
 		return right
 	}
 
-	if isComparisonOp(expr.Operator) {
-		return EvaluateComp(left, right, expr.Operator, expr.Context())
-	}
-	return EvaluateSides(left, right, expr.Operator, expr.Context())
+	return e.operate(left, right, expr.Operator, expr.Context())
 }
 
 // EvaluateComp compares two values to see if they are equal
@@ -169,7 +167,40 @@ func EvaluateSides(left, right object.Object, op string, con lexer.Context) obje
 			Con: con,
 		}
 	case *object.String:
-		panic("eval.evaluateSides (case String): unimplemented")
+		if right, ok := right.(*object.String); ok {
+			if op != lexer.ADD {
+				return &object.Exception{
+					Msg: fmt.Sprintf("Cannot use operator `%s` on STR", op),
+					Con: con,
+				}
+			}
+			left := left.(*object.String)
+			return &object.String{Value: left.Value + right.Value}
+		}
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot operate on STR and %T", right),
+			Con: con,
+		}
+	case *object.Set:
+		if right, ok := right.(*object.Set); ok {
+			left := left.(*object.Set)
+			switch op {
+			case lexer.BWOR:
+				return left.Union(right)
+			case lexer.BWAND:
+				return left.Intersect(right)
+			case lexer.SUB:
+				return left.Difference(right)
+			}
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use operator `%s` on SET", op),
+				Con: con,
+			}
+		}
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot operate on SET and %T", right),
+			Con: con,
+		}
 	case *object.Boolean:
 		return &object.Exception{
 			Msg: fmt.Sprintf("Cannot operate `%s` on BOOL", op),
@@ -193,6 +224,203 @@ func EvaluateSides(left, right object.Object, op string, con lexer.Context) obje
 	}
 }
 
+// Contains reports whether elem is in a set, is a key of a map,
+// or is a substring of a string
+func Contains(container, elem object.Object, con lexer.Context) object.Object {
+	switch container := container.(type) {
+	case *object.Set:
+		key, ok := elem.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as element of Set", elem),
+				Con: con,
+			}
+		}
+		return nativeBooltoObj(container.Has(key))
+
+	case *object.Map:
+		key, ok := elem.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as key for Map", elem),
+				Con: con,
+			}
+		}
+		_, ok = container.Get(key)
+		return nativeBooltoObj(ok)
+
+	case *object.String:
+		sub, ok := elem.(*object.String)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot search for type %T in STR", elem),
+				Con: con,
+			}
+		}
+		return nativeBooltoObj(strings.Contains(container.Value, sub.Value))
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot use operator `in` on type %T", container),
+			Con: con,
+		}
+	}
+}
+
+// Concat joins the string forms of several objects into a String
+func Concat(parts []object.Object) object.Object {
+	var out strings.Builder
+
+	for _, part := range parts {
+		out.WriteString(part.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
+// Index gets the element of an array, tuple, string or map at index
+func Index(left, index object.Object, con lexer.Context) object.Object {
+	switch left := left.(type) {
+	case *object.Tuple:
+		pos, ok := index.(*object.Integer)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot index into tuple with index of type %T", index),
+				Con: con,
+			}
+		}
+		i, ok := wrapIndex(pos.Value, len(left.Elements))
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot get index %d of tuple of length %d", pos.Value, len(left.Elements)),
+				Con: con,
+			}
+		}
+		return left.Elements[i]
+
+	case *object.Array:
+		pos, ok := index.(*object.Integer)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot index into array with index of type %T", index),
+				Con: con,
+			}
+		}
+		i, ok := wrapIndex(pos.Value, len(left.Elements))
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot get index %d of array of length %d", pos.Value, len(left.Elements)),
+				Con: con,
+			}
+		}
+		return left.Elements[i]
+
+	case *object.String:
+		pos, ok := index.(*object.Integer)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot index into string with index of type %T", index),
+				Con: con,
+			}
+		}
+		// strings are indexed by character rather than by byte
+		chars := []rune(left.Value)
+		i, ok := wrapIndex(pos.Value, len(chars))
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot get index %d of string of length %d", pos.Value, len(chars)),
+				Con: con,
+			}
+		}
+		return &object.String{Value: string(chars[i])}
+
+	case *object.Map:
+		hashable, ok := index.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as key for Map", index),
+				Con: con,
+			}
+		}
+		ret, ok := left.Get(hashable)
+		if !ok {
+			return NULL
+		}
+		return ret
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot use type %T as index", left),
+			Con: con,
+		}
+	}
+}
+
+// wrapIndex turns a possibly negative index into a collection of the given
+// length into a position from the start, where -1 is the last element.
+// It reports whether the index is in range.
+func wrapIndex(index int64, length int) (int, bool) {
+	if index < 0 {
+		index += int64(length)
+	}
+	if index < 0 || index >= int64(length) {
+		return 0, false
+	}
+	return int(index), true
+}
+
+// SetIndex sets the element of an array or map at index to val, returning val
+func SetIndex(left, index, val object.Object, con lexer.Context) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		pos, ok := index.(*object.Integer)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot index into array with index of type %T", index),
+				Con: con,
+			}
+		}
+		i, ok := wrapIndex(pos.Value, len(left.Elements))
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot set index %d of array of length %d", pos.Value, len(left.Elements)),
+				Con: con,
+			}
+		}
+		left.Elements[i] = val
+		return val
+
+	case *object.Map:
+		hashable, ok := index.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as key for Map", index),
+				Con: con,
+			}
+		}
+		left.Set(hashable, val)
+		return val
+
+	case *object.String:
+		return &object.Exception{
+			Msg: "Cannot assign to an index of a string, strings are immutable",
+			Con: con,
+		}
+
+	case *object.Tuple:
+		return &object.Exception{
+			Msg: "Cannot assign to an index of a tuple, tuples are immutable",
+			Con: con,
+		}
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot use type %T as index", left),
+			Con: con,
+		}
+	}
+}
+
 func executeCompInt(left, right int64, op string) bool {
 	switch op {
 	case lexer.EQ:
@@ -354,7 +582,8 @@ func isComparisonOp(input string) bool {
 	}
 }
 
-func evaluateTruthiness(in object.Object) bool {
+// EvaluateTruthiness reports whether a value counts as true in a condition
+func EvaluateTruthiness(in object.Object) bool {
 	switch in.(type) {
 	case *object.Integer:
 		if in.(*object.Integer).Value == 0 {
@@ -387,3 +616,216 @@ func nativeBooltoObj(input bool) object.Object {
 	}
 	return FALSE
 }
+
+// Iterate returns an iterator over an array, tuple, string, map, set or range.
+// With a single loop variable, iterating over a map gives its keys,
+// and over anything else gives its elements.
+func Iterate(iterable object.Object, vars int, con lexer.Context) object.Object {
+	i := 0
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// iterate over a copy so the loop body can modify the array
+		elements := make([]object.Object, len(iterable.Elements))
+		copy(elements, iterable.Elements)
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(elements) {
+				return nil, nil, false
+			}
+			i++
+			return &object.Integer{Value: int64(i - 1)}, elements[i-1], true
+		}}
+
+	case *object.Tuple:
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(iterable.Elements) {
+				return nil, nil, false
+			}
+			i++
+			return &object.Integer{Value: int64(i - 1)}, iterable.Elements[i-1], true
+		}}
+
+	case *object.String:
+		chars := []rune(iterable.Value)
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(chars) {
+				return nil, nil, false
+			}
+			i++
+			return &object.Integer{Value: int64(i - 1)}, &object.String{Value: string(chars[i-1])}, true
+		}}
+
+	case *object.Set:
+		items := iterable.Items()
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(items) {
+				return nil, nil, false
+			}
+			i++
+			return &object.Integer{Value: int64(i - 1)}, items[i-1], true
+		}}
+
+	case *object.Map:
+		pairs := iterable.Pairs()
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if i >= len(pairs) {
+				return nil, nil, false
+			}
+			i++
+			pair := pairs[i-1]
+			if vars == 1 {
+				return pair.Key, pair.Key, true
+			}
+			return pair.Key, pair.Value, true
+		}}
+
+	case *object.Range:
+		length := iterable.Len()
+		return &object.Iterator{Next: func() (object.Object, object.Object, bool) {
+			if int64(i) >= length {
+				return nil, nil, false
+			}
+			i++
+			num := iterable.Start + int64(i-1)*iterable.Step
+			return &object.Integer{Value: int64(i - 1)}, &object.Integer{Value: num}, true
+		}}
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot iterate over type %T", iterable),
+			Con: con,
+		}
+	}
+}
+
+// MakeRange creates a range from the values of a range literal.
+// start and step may be NULL, in which case they default to 0 and 1.
+func MakeRange(start, end, step object.Object, inclusive bool, con lexer.Context) object.Object {
+	rng := &object.Range{Start: 0, Step: 1}
+
+	var exc object.Object
+	if rng.Start, exc = rangeBound(start, 0, "start", con); exc != nil {
+		return exc
+	}
+	if rng.End, exc = rangeBound(end, 0, "end", con); exc != nil {
+		return exc
+	}
+	if rng.Step, exc = rangeBound(step, 1, "step", con); exc != nil {
+		return exc
+	}
+	if rng.Step == 0 {
+		return &object.Exception{
+			Msg: "Step of range cannot be zero",
+			Con: con,
+		}
+	}
+
+	if inclusive {
+		if rng.Step > 0 {
+			rng.End++
+		} else {
+			rng.End--
+		}
+	}
+	return rng
+}
+
+// Slice returns the part of an array, tuple or string covered by a range.
+// Any of start, end and step may be NULL, to slice from the beginning,
+// to the end, or every element. Negative bounds count from the end,
+// and bounds past either end are clamped.
+func Slice(left, start, end, step object.Object, inclusive bool, con lexer.Context) object.Object {
+	var length int64
+	switch left := left.(type) {
+	case *object.Array:
+		length = int64(len(left.Elements))
+	case *object.Tuple:
+		length = int64(len(left.Elements))
+	case *object.String:
+		length = int64(len([]rune(left.Value)))
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot slice type %T", left),
+			Con: con,
+		}
+	}
+
+	from, exc := rangeBound(start, 0, "start", con)
+	if exc != nil {
+		return exc
+	}
+	to, exc := rangeBound(end, length, "end", con)
+	if exc != nil {
+		return exc
+	}
+	by, exc := rangeBound(step, 1, "step", con)
+	if exc != nil {
+		return exc
+	}
+	if by <= 0 {
+		return &object.Exception{
+			Msg: "Step of slice must be positive",
+			Con: con,
+		}
+	}
+	// negative bounds count from the end
+	if from < 0 {
+		from += length
+	}
+	if to < 0 {
+		to += length
+	}
+	if inclusive && !object.IsNull(end) {
+		to++
+	}
+
+	from, to = clamp(from, 0, length), clamp(to, 0, length)
+
+	switch left := left.(type) {
+	case *object.Array:
+		elements := []object.Object{}
+		for i := from; i < to; i += by {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Array{Elements: elements}
+	case *object.Tuple:
+		elements := []object.Object{}
+		for i := from; i < to; i += by {
+			elements = append(elements, left.Elements[i])
+		}
+		return &object.Tuple{Elements: elements}
+	default:
+		chars := []rune(left.(*object.String).Value)
+		sliced := []rune{}
+		for i := from; i < to; i += by {
+			sliced = append(sliced, chars[i])
+		}
+		return &object.String{Value: string(sliced)}
+	}
+}
+
+// rangeBound gets the value of a bound of a range or slice, which must be
+// an integer, or NULL to use the default value
+func rangeBound(bound object.Object, def int64, what string, con lexer.Context) (int64, object.Object) {
+	if object.IsNull(bound) {
+		return def, nil
+	}
+	num, ok := bound.(*object.Integer)
+	if !ok {
+		return 0, &object.Exception{
+			Msg: fmt.Sprintf("Cannot use type %T as %s of range", bound, what),
+			Con: con,
+		}
+	}
+	return num.Value, nil
+}
+
+func clamp(n, low, high int64) int64 {
+	if n < low {
+		return low
+	}
+	if n > high {
+		return high
+	}
+	return n
+}
@@ -0,0 +1,190 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/lexer"
+	"github.com/cartoon-raccoon/lemur/object"
+	"github.com/cartoon-raccoon/lemur/parser"
+)
+
+// SourceExt is the extension of Lemur source files
+const SourceExt = ".lm"
+
+// PathVar is the environment variable holding the module search path.
+// It is a list of directories, separated like PATH.
+const PathVar = "LEMURPATH"
+
+// Loader finds and evaluates the modules named in import statements.
+//
+// A module is looked for next to the file that imports it, then in each
+// of the search paths in order. Each file is only evaluated once, and
+// every import of it after that gets the same module.
+type Loader struct {
+	Paths []string
+	// modules caches loaded modules by absolute path
+	modules map[string]*object.Module
+	// loading is the chain of modules currently being evaluated,
+	// used to detect import cycles
+	loading []*object.Module
+	// warnings holds the warnings from parsing every file evaluated,
+	// including modules, so that they reach the file that imported them
+	warnings []error
+}
+
+// NewLoader returns a loader that searches the given paths
+func NewLoader(paths ...string) *Loader {
+	return &Loader{
+		Paths:   paths,
+		modules: make(map[string]*object.Module),
+	}
+}
+
+// SearchPath returns the directories listed in LEMURPATH
+func SearchPath() []string {
+	paths := []string{}
+	for _, path := range filepath.SplitList(os.Getenv(PathVar)) {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// resolve finds the file for the module name, looking in dir first.
+// It returns the absolute path of the file, or false if there is none.
+func (l *Loader) resolve(name string, dir string) (string, bool) {
+	for _, search := range append([]string{dir}, l.Paths...) {
+		path, err := filepath.Abs(filepath.Join(search, name+SourceExt))
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Load returns the module with the given name, as imported from a file in dir.
+// If the module has not been loaded yet, it is evaluated first.
+func (l *Loader) Load(name string, dir string, con lexer.Context) object.Object {
+	path, ok := l.resolve(name, dir)
+	if !ok {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Could not find module %s", name),
+			Con: con,
+		}
+	}
+
+	for i, mod := range l.loading {
+		if mod.Path == path {
+			chain := []string{}
+			for _, mod := range l.loading[i:] {
+				chain = append(chain, mod.Name)
+			}
+			chain = append(chain, name)
+			return &object.Exception{
+				Msg: fmt.Sprintf("Import cycle: %s", strings.Join(chain, " -> ")),
+				Con: con,
+			}
+		}
+	}
+
+	if mod, ok := l.modules[path]; ok {
+		return mod
+	}
+
+	mod := &object.Module{Name: name, Path: path, Env: object.NewEnv()}
+	l.loading = append(l.loading, mod)
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	e := New()
+	e.loader = l
+	res := e.EvaluateFile(path, mod.Env)
+	if object.IsErr(res) {
+		return res
+	}
+
+	l.modules[path] = mod
+	return mod
+}
+
+// EvaluateFile parses and evaluates the source file at path in env.
+// Imports in the file are resolved relative to its directory.
+// The first exception raised by the file is returned in place of its results.
+func (e *Evaluator) EvaluateFile(path string, env *object.Environment) object.Object {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Could not read %s: %s", path, err),
+			Con: e.Ctxt,
+		}
+	}
+
+	p, err := parser.New(lexer.New(string(src)))
+	if err != nil {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Could not parse %s: %s", path, err),
+			Con: e.Ctxt,
+		}
+	}
+	prog := p.Parse()
+	if errs := p.CheckErrors(); errs != nil {
+		msgs := []string{}
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		return &object.Exception{
+			Msg: fmt.Sprintf("Could not parse %s:\n%s", path, strings.Join(msgs, "\n")),
+			Con: e.Ctxt,
+		}
+	}
+	for _, warning := range p.Warnings() {
+		e.loader.warnings = append(e.loader.warnings, fmt.Errorf("%s: %s", path, warning.Error()))
+	}
+
+	dir := e.dir
+	e.dir = filepath.Dir(path)
+	defer func() { e.dir = dir }()
+
+	res := e.Evaluate(prog, env)
+	if results, ok := res.(*object.StmtResults); ok && len(results.Results) > 0 {
+		if last := results.Results[len(results.Results)-1]; object.IsErr(last) {
+			return last
+		}
+	}
+	return res
+}
+
+// Warnings returns the warnings from parsing the files evaluated so far,
+// each prefixed with the path of its file
+func (e *Evaluator) Warnings() []error {
+	return e.loader.warnings
+}
+
+func (e *Evaluator) evalImportStatement(stmt *ast.ImportStatement, env *object.Environment) object.Object {
+	for _, name := range stmt.Names {
+		mod := e.loader.Load(name.Value, e.dir, name.Context())
+		if object.IsErr(mod) {
+			return mod
+		}
+		env.Set(name.Value, mod)
+	}
+	return NULL
+}
+
+// moduleMember looks up a top level binding of a module
+func moduleMember(mod *object.Module, ident *ast.Identifier) object.Object {
+	if member, ok := mod.Env.Data[ident.Value]; ok {
+		return member
+	}
+	return &object.Exception{
+		Msg: fmt.Sprintf("Module %s has no member %s", mod.Name, ident.Value),
+		Con: ident.Context(),
+	}
+}
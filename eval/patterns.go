@@ -0,0 +1,273 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/object"
+)
+
+// bind binds the names in a pattern to the parts of val that they match.
+// It returns an exception if val does not have the shape of the pattern.
+func bind(pattern ast.Pattern, val object.Object, env *object.Environment) object.Object {
+	if exc, _ := destructure(pattern, val, env); exc != nil {
+		return exc
+	}
+	return NULL
+}
+
+// destructure binds the names in a pattern to the parts of val that they
+// match. If val does not match the pattern, it returns an exception saying
+// why, along with true. Any other exception, such as from a pattern naming
+// a class that does not exist, is returned along with false.
+func destructure(pattern ast.Pattern, val object.Object, env *object.Environment) (object.Object, bool) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		env.Set(pattern.Value, val)
+		return nil, false
+
+	case *ast.WildcardPattern:
+		return nil, false
+
+	case *ast.LiteralPattern:
+		lit := literalValue(pattern.Value)
+		if !literalEqual(lit, val) {
+			return mismatch(fmt.Sprintf("Expected %s, got %s", lit.Inspect(), val.Inspect()), pattern)
+		}
+		return nil, false
+
+	case *ast.RangePattern:
+		num, ok := val.(*object.Integer)
+		if !ok {
+			return mismatch(fmt.Sprintf("Cannot match type %T against a range", val), pattern)
+		}
+		start := literalValue(pattern.Start).(*object.Integer).Value
+		end := literalValue(pattern.End).(*object.Integer).Value
+		if num.Value < start || num.Value > end || (num.Value == end && !pattern.Inclusive) {
+			return mismatch(fmt.Sprintf("%d is not in the range %s", num.Value, pattern.String()), pattern)
+		}
+		return nil, false
+
+	case *ast.TuplePattern:
+		tuple, ok := val.(*object.Tuple)
+		if !ok {
+			return mismatch(fmt.Sprintf("Cannot destructure type %T as a tuple", val), pattern)
+		}
+		return destructureSequence(pattern, pattern.Elements, tuple.Elements, "tuple", env)
+
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return mismatch(fmt.Sprintf("Cannot destructure type %T as an array", val), pattern)
+		}
+		return destructureSequence(pattern, pattern.Elements, arr.Elements, "array", env)
+
+	case *ast.MapPattern:
+		for i, key := range pattern.Keys {
+			elem, exc := destructureKey(val, key)
+			if exc != nil {
+				return exc, true
+			}
+			if exc, mismatched := destructure(pattern.Values[i], elem, env); exc != nil {
+				return exc, mismatched
+			}
+		}
+		return nil, false
+
+	case *ast.ClassPattern:
+		obj, ok := env.Get(pattern.Class.Value)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Unknown class %s", pattern.Class.Value),
+				Con: pattern.Class.Context(),
+			}, false
+		}
+		class, ok := obj.(*object.Class)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("%s is not a class", pattern.Class.Value),
+				Con: pattern.Class.Context(),
+			}, false
+		}
+		inst, ok := val.(*object.Instance)
+		if !ok {
+			return mismatch(fmt.Sprintf("Expected an instance of %s, got %T", class.Name, val), pattern)
+		}
+		if inst.Class != class {
+			return mismatch(fmt.Sprintf("Expected an instance of %s, got %s", class.Name, inst.Class.Name), pattern)
+		}
+		return destructure(pattern.Fields, val, env)
+
+	default:
+		return &object.Exception{
+			Msg: fmt.Sprintf("Unknown pattern %s", pattern.String()),
+			Con: pattern.Context(),
+		}, false
+	}
+}
+
+// mismatch returns an exception for a value that does not match a pattern
+func mismatch(msg string, pattern ast.Pattern) (object.Object, bool) {
+	return &object.Exception{Msg: msg, Con: pattern.Context()}, true
+}
+
+// destructureSequence binds the elements of a tuple or array to patterns.
+// A rest pattern among them takes the elements that the patterns before
+// and after it do not, as a value of the same kind as the sequence.
+func destructureSequence(
+	pattern ast.Pattern,
+	patterns []ast.Pattern,
+	elems []object.Object,
+	kind string,
+	env *object.Environment,
+) (object.Object, bool) {
+	rest := -1
+	for i, elem := range patterns {
+		if _, ok := elem.(*ast.RestPattern); ok {
+			rest = i
+		}
+	}
+
+	if rest < 0 && len(elems) != len(patterns) {
+		return mismatch(
+			fmt.Sprintf("Expected %s of length %d, got %d", kind, len(patterns), len(elems)),
+			pattern,
+		)
+	}
+	if rest >= 0 && len(elems) < len(patterns)-1 {
+		return mismatch(
+			fmt.Sprintf("Expected %s of at least length %d, got %d", kind, len(patterns)-1, len(elems)),
+			pattern,
+		)
+	}
+
+	for i, elem := range patterns {
+		var val object.Object
+		switch {
+		case i == rest:
+			after := len(patterns) - rest - 1
+			rem := make([]object.Object, len(elems)-rest-after)
+			copy(rem, elems[rest:len(elems)-after])
+			if kind == "tuple" {
+				val = &object.Tuple{Elements: rem}
+			} else {
+				val = &object.Array{Elements: rem}
+			}
+			elem = elem.(*ast.RestPattern).Name
+		case rest >= 0 && i > rest:
+			// patterns after the rest match from the end
+			val = elems[len(elems)-(len(patterns)-i)]
+		default:
+			val = elems[i]
+		}
+		if exc, mismatched := destructure(elem, val, env); exc != nil {
+			return exc, mismatched
+		}
+	}
+	return nil, false
+}
+
+// destructureKey gets the value that a key of a map pattern matches,
+// which is the value of a string key in a map or a field of an instance
+func destructureKey(val object.Object, key *ast.Identifier) (object.Object, object.Object) {
+	switch val := val.(type) {
+	case *object.Map:
+		elem, ok := val.Get(&object.String{Value: key.Value})
+		if !ok {
+			return nil, &object.Exception{
+				Msg: fmt.Sprintf("Map has no key %q", key.Value),
+				Con: key.Context(),
+			}
+		}
+		return elem, nil
+
+	case *object.Instance:
+		elem, ok := val.Fields[key.Value]
+		if !ok {
+			return nil, &object.Exception{
+				Msg: fmt.Sprintf("%s has no field %s", val.Class.Name, key.Value),
+				Con: key.Context(),
+			}
+		}
+		return elem, nil
+
+	default:
+		return nil, &object.Exception{
+			Msg: fmt.Sprintf("Cannot destructure type %T as a map", val),
+			Con: key.Context(),
+		}
+	}
+}
+
+// literalValue returns the value of the literal in a literal or range pattern
+func literalValue(lit ast.Expression) object.Object {
+	switch lit := lit.(type) {
+	case *ast.Int:
+		return &object.Integer{Value: lit.Inner}
+	case *ast.Flt:
+		return &object.Float{Value: lit.Inner}
+	case *ast.Str:
+		return &object.String{Value: lit.Inner}
+	case *ast.Bool:
+		return nativeBooltoObj(lit.Inner)
+	case *ast.PrefixExpr:
+		// the parser only allows negated numbers
+		switch val := literalValue(lit.Right).(type) {
+		case *object.Integer:
+			return &object.Integer{Value: -val.Value}
+		case *object.Float:
+			return &object.Float{Value: -val.Value}
+		}
+	}
+	return NULL
+}
+
+// literalEqual reports whether val is equal to the value of a literal.
+// Values of different types are never equal, so 1 does not match 1.0.
+func literalEqual(lit, val object.Object) bool {
+	switch lit := lit.(type) {
+	case *object.Integer:
+		val, ok := val.(*object.Integer)
+		return ok && val.Value == lit.Value
+	case *object.Float:
+		val, ok := val.(*object.Float)
+		return ok && val.Value == lit.Value
+	case *object.String:
+		val, ok := val.(*object.String)
+		return ok && val.Value == lit.Value
+	case *object.Boolean:
+		val, ok := val.(*object.Boolean)
+		return ok && val.Value == lit.Value
+	default:
+		return false
+	}
+}
+
+func (e *Evaluator) evalMatchExpr(expr *ast.MatchExpr, env *object.Environment) object.Object {
+	subject := e.Evaluate(expr.Subject, env)
+	if object.IsErr(subject) {
+		return subject
+	}
+
+	for _, arm := range expr.Arms {
+		// names bound by an arm are only visible in its guard and body
+		armEnv := object.NewEnclosedEnv(env)
+		if exc, mismatched := destructure(arm.Pattern, subject, armEnv); exc != nil {
+			if mismatched {
+				continue
+			}
+			return exc
+		}
+		if arm.Guard != nil {
+			guard := e.Evaluate(arm.Guard, armEnv)
+			if object.IsErr(guard) {
+				return guard
+			}
+			if !EvaluateTruthiness(guard) {
+				continue
+			}
+		}
+		return e.Evaluate(arm.Body, armEnv)
+	}
+	return NULL
+}
@@ -0,0 +1,290 @@
+package eval
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/lexer"
+	"github.com/cartoon-raccoon/lemur/object"
+)
+
+// builtinTraits can be implemented by classes to overload operators
+// and to be used as map keys
+var builtinTraits = map[string]*object.Trait{
+	// Add overloads +, with add(self, other)
+	"Add": {Name: "Add", Methods: map[string]int{"add": 2}},
+	// Sub overloads -, with sub(self, other)
+	"Sub": {Name: "Sub", Methods: map[string]int{"sub": 2}},
+	// Mul overloads *, with mul(self, other)
+	"Mul": {Name: "Mul", Methods: map[string]int{"mul": 2}},
+	// Div overloads /, with div(self, other)
+	"Div": {Name: "Div", Methods: map[string]int{"div": 2}},
+	// Eq overloads == and !=, with eq(self, other) returning whether they are equal
+	"Eq": {Name: "Eq", Methods: map[string]int{"eq": 2}},
+	// Ord overloads <, >, <= and >=, with cmp(self, other) returning
+	// an integer that is negative, zero or positive
+	"Ord": {Name: "Ord", Methods: map[string]int{"cmp": 2}},
+	// Hash allows instances to be map keys, with hash(self)
+	// returning an integer, string, boolean or tuple to hash
+	"Hash": {Name: "Hash", Methods: map[string]int{"hash": 1}},
+}
+
+// overload is the trait method that an operator is dispatched to
+type overload struct {
+	trait  string
+	method string
+}
+
+var operatorTraits = map[string]overload{
+	lexer.ADD: {"Add", "add"},
+	lexer.SUB: {"Sub", "sub"},
+	lexer.MUL: {"Mul", "mul"},
+	lexer.DIV: {"Div", "div"},
+	lexer.EQ:  {"Eq", "eq"},
+	lexer.NE:  {"Eq", "eq"},
+	lexer.LT:  {"Ord", "cmp"},
+	lexer.GT:  {"Ord", "cmp"},
+	lexer.LE:  {"Ord", "cmp"},
+	lexer.GE:  {"Ord", "cmp"},
+}
+
+// newTrait creates a trait from its declaration
+func newTrait(decl *ast.TraitDecl) *object.Trait {
+	trait := &object.Trait{Name: decl.Name.Value, Methods: make(map[string]int)}
+	for _, sig := range decl.Methods {
+		trait.Methods[sig.Name.Value] = len(sig.Params)
+	}
+	return trait
+}
+
+// implement checks that an impl defines exactly the methods of its trait,
+// then adds them to its class.
+func implement(decl *ast.ImplDecl, env *object.Environment) object.Object {
+	var trait *object.Trait
+	if obj, ok := env.Get(decl.Trait.Value); ok {
+		if trait, ok = obj.(*object.Trait); !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("%s is not a trait", decl.Trait.Value),
+				Con: decl.Trait.Context(),
+			}
+		}
+	} else if trait, ok = builtinTraits[decl.Trait.Value]; !ok {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Unknown trait %s", decl.Trait.Value),
+			Con: decl.Trait.Context(),
+		}
+	}
+
+	obj, ok := env.Get(decl.Class.Value)
+	if !ok {
+		return &object.Exception{
+			Msg: fmt.Sprintf("Unknown class %s", decl.Class.Value),
+			Con: decl.Class.Context(),
+		}
+	}
+	class, ok := obj.(*object.Class)
+	if !ok {
+		return &object.Exception{
+			Msg: fmt.Sprintf("%s is not a class", decl.Class.Value),
+			Con: decl.Class.Context(),
+		}
+	}
+	if class.Implements(trait.Name) {
+		return &object.Exception{
+			Msg: fmt.Sprintf("%s already implements %s", class.Name, trait.Name),
+			Con: decl.Context(),
+		}
+	}
+
+	defined := map[string]bool{}
+	for _, method := range decl.Methods {
+		name := method.Name.Value
+		params, ok := trait.Methods[name]
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("%s is not a method of trait %s", name, trait.Name),
+				Con: method.Name.Context(),
+			}
+		}
+		if len(method.Params) != params {
+			return &object.Exception{
+				Msg: fmt.Sprintf(
+					"Method %s of trait %s takes %d parameters, got %d",
+					name, trait.Name, params, len(method.Params),
+				),
+				Con: method.Name.Context(),
+			}
+		}
+		if _, ok := class.Methods[name]; ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("%s already has a member %s", class.Name, name),
+				Con: method.Name.Context(),
+			}
+		}
+		defined[name] = true
+	}
+
+	missing := []string{}
+	for name := range trait.Methods {
+		if !defined[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &object.Exception{
+			Msg: fmt.Sprintf(
+				"Impl of %s for %s is missing %s",
+				trait.Name, class.Name, strings.Join(missing, ", "),
+			),
+			Con: decl.Context(),
+		}
+	}
+
+	for _, method := range decl.Methods {
+		class.Methods[method.Name.Value] = &object.Function{
+			Params: method.Params,
+			Body:   method.Body,
+			Env:    env,
+		}
+	}
+	class.Traits[trait.Name] = trait
+	return NULL
+}
+
+// operate applies an infix operator to two objects.
+// If the left side is an instance, the operator is dispatched to the method
+// of the trait that overloads it, and instances that do not implement Eq
+// are only equal to themselves. Membership with in is checked by the
+// right side, so it is never overloaded.
+func (e *Evaluator) operate(left, right object.Object, op string, con lexer.Context) object.Object {
+	if op == lexer.IN {
+		switch right.(type) {
+		case *object.Set, *object.Map:
+			left = e.mapKey(left, con)
+			if object.IsErr(left) {
+				return left
+			}
+		}
+		return Contains(right, left, con)
+	}
+
+	inst, ok := left.(*object.Instance)
+	if !ok {
+		if isComparisonOp(op) {
+			return EvaluateComp(left, right, op, con)
+		}
+		return EvaluateSides(left, right, op, con)
+	}
+
+	overload, ok := operatorTraits[op]
+	if !ok || !inst.Class.Implements(overload.trait) {
+		switch op {
+		case lexer.EQ:
+			return nativeBooltoObj(left == right)
+		case lexer.NE:
+			return nativeBooltoObj(left != right)
+		}
+		return &object.Exception{
+			Msg: fmt.Sprintf("Cannot use operator `%s` on %s", op, inst.Class.Name),
+			Con: con,
+		}
+	}
+
+	res := e.callMethod(inst, overload.method, right)
+	if object.IsErr(res) {
+		return res
+	}
+
+	switch overload.trait {
+	case "Eq":
+		equal := EvaluateTruthiness(res)
+		return nativeBooltoObj(equal == (op == lexer.EQ))
+	case "Ord":
+		cmp, ok := res.(*object.Integer)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("cmp of %s must return an integer, got %T", inst.Class.Name, res),
+				Con: con,
+			}
+		}
+		return nativeBooltoObj(executeCompInt(cmp.Value, 0, op))
+	default:
+		return res
+	}
+}
+
+// mapKey returns what to use as the key for index in a Map.
+// Instances are keyed by the result of their Hash method, tuples
+// by their elements, and every other object is its own key.
+func (e *Evaluator) mapKey(index object.Object, con lexer.Context) object.Object {
+	switch index.(type) {
+	case *object.Instance, *object.Tuple:
+		hash, exc := e.hashKey(index, con)
+		if exc != nil {
+			return exc
+		}
+		return &object.Key{Object: index, Hash: hash}
+	default:
+		return index
+	}
+}
+
+// hashKey computes the hash of a map key. Tuples are hashed from their
+// elements, which must all be hashable.
+func (e *Evaluator) hashKey(key object.Object, con lexer.Context) (object.HashKey, object.Object) {
+	switch key := key.(type) {
+	case *object.Instance:
+		if !key.Class.Implements("Hash") {
+			return object.HashKey{}, &object.Exception{
+				Msg: fmt.Sprintf("Cannot use %s as key for Map, as it does not implement Hash", key.Class.Name),
+				Con: con,
+			}
+		}
+		res := e.callMethod(key, "hash")
+		if object.IsErr(res) {
+			return object.HashKey{}, res
+		}
+		switch res.(type) {
+		case object.Hashable, *object.Instance, *object.Tuple:
+		default:
+			return object.HashKey{}, &object.Exception{
+				Msg: fmt.Sprintf("hash of %s must return a hashable value, got %T", key.Class.Name, res),
+				Con: con,
+			}
+		}
+		hash, exc := e.hashKey(res, con)
+		if exc != nil {
+			return hash, exc
+		}
+		return object.HashKey{Type: key.Class.Name, Value: hash.Value}, nil
+
+	case *object.Tuple:
+		keys := []object.HashKey{}
+		for _, elem := range key.Elements {
+			hash, exc := e.hashKey(elem, con)
+			if exc != nil {
+				return hash, exc
+			}
+			keys = append(keys, hash)
+		}
+		return object.CombineHashKeys(keys), nil
+
+	case object.Hashable:
+		return key.HashKey(), nil
+
+	default:
+		return object.HashKey{}, &object.Exception{
+			Msg: fmt.Sprintf("Cannot use type %T as key for Map", key),
+			Con: con,
+		}
+	}
+}
+
+// callMethod calls a method of an instance with the given arguments
+func (e *Evaluator) callMethod(inst *object.Instance, name string, args ...object.Object) object.Object {
+	method := &object.BoundMethod{Name: name, Receiver: inst, Method: inst.Class.Methods[name]}
+	return e.applyFunction(method, args, nil)
+}
@@ -1,94 +1,139 @@
 package lexer
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 /*------------Lexer------------*/
 
 // Lexer represents the FSM that tokenizes the input to the interpreter
 type Lexer struct {
-	input   string
+	// input holds the source text, or when lexing from a reader, the part
+	// of it that has been read and not yet discarded. base is the offset
+	// of input's first byte from the start of the source.
+	input  string
+	base   int
+	reader *bufio.Reader
+	// readErr is set if reading from the source failed
+	readErr error
+
+	// line and col are the position of ch, and pos is its byte offset
 	line    int
 	col     int
 	pos     int
-	context string
-	lastln  int
 	readPos int
-	ch      byte
+	ch      rune
+	// context is the text of the line that ch is on
+	context string
+	// interps tracks the interpolated strings that are open, holding the
+	// number of unclosed braces inside each interpolated expression
+	interps []int
 }
 
 // New returns a new uninitialized lexer
 func New(input string) *Lexer {
-	l := &Lexer{input: input, line: 1, col: 1}
-	l.nextChar()
+	l := &Lexer{input: input}
+	l.init()
+	return l
+}
+
+// NewReader returns a lexer that reads its input from r as it is needed,
+// instead of requiring the whole program up front. The tokens it produces
+// are identical to those produced by New for the same text.
+func NewReader(r io.Reader) *Lexer {
+	l := &Lexer{reader: bufio.NewReader(r)}
+	l.init()
 	return l
 }
 
-// Tokenize fully advances the lexer and returns a slice of tokens
+func (l *Lexer) init() {
+	l.line = 1
+	l.col = 1
+	l.context = l.lineAt(0)
+	l.readChar()
+}
+
+// Tokenize fully advances the lexer and returns a slice of tokens,
+// not including the EOF. It stops at the first error it encounters.
 func (l *Lexer) Tokenize() (tokens []Token, err error) {
 	tokens = make([]Token, 0)
-	tok, err := l.NextToken()
-	for !tok.isEOF() {
+	for {
+		tok, err := l.NextToken()
 		if err != nil {
-			return
+			return tokens, err
+		}
+		if tok.isEOF() {
+			return tokens, nil
 		}
-		tok, err = l.NextToken()
 		tokens = append(tokens, tok)
 	}
-	return
 }
 
 // NextToken advances the lexer and produces a token
 func (l *Lexer) NextToken() (Token, error) {
-	l.skipWhitespace()
+	l.discard()
+	if err := l.skipComments(); err != nil {
+		return newToken(ILLEGAL, ILLEGAL, l.current()), err
+	}
+
+	start := l.current()
 
 	switch {
 
 	case l.ch == '=':
 		l.nextChar()
-		if l.ch == '=' {
+		switch l.ch {
+		case '=':
 			l.nextChar()
-			return newToken(EQ, EQ, l.line, l.col, l.context), nil
+			return newToken(EQ, EQ, l.span(start)), nil
+		case '>':
+			l.nextChar()
+			return newToken(FATARROW, FATARROW, l.span(start)), nil
+		default:
+			return newToken(ASSIGN, ASSIGN, l.span(start)), nil
 		}
-		return newToken(ASSIGN, ASSIGN, l.line, l.col, l.context), nil
 
 	case l.ch == '+':
 		l.nextChar()
 		if l.ch == '=' {
 			l.nextChar()
-			return newToken(ADDASSIGN, ADDASSIGN, l.line, l.col, l.context), nil
+			return newToken(ADDASSIGN, ADDASSIGN, l.span(start)), nil
 		}
-		return newToken(ADD, ADD, l.line, l.col, l.context), nil
+		return newToken(ADD, ADD, l.span(start)), nil
 
 	case l.ch == '-':
 		l.nextChar()
 		switch l.ch {
 		case '=':
 			l.nextChar()
-			return newToken(SUBASSIGN, SUBASSIGN, l.line, l.col, l.context), nil
+			return newToken(SUBASSIGN, SUBASSIGN, l.span(start)), nil
 		case '>':
 			l.nextChar()
-			return newToken(RETSIG, RETSIG, l.line, l.col, l.context), nil
+			return newToken(RETSIG, RETSIG, l.span(start)), nil
 		default:
-			return newToken(SUB, SUB, l.line, l.col, l.context), nil
+			return newToken(SUB, SUB, l.span(start)), nil
 		}
 
 	case l.ch == '*':
 		l.nextChar()
 		if l.ch == '=' {
 			l.nextChar()
-			return newToken(MULASSIGN, MULASSIGN, l.line, l.col, l.context), nil
+			return newToken(MULASSIGN, MULASSIGN, l.span(start)), nil
 		}
-		return newToken(MUL, MUL, l.line, l.col, l.context), nil
+		return newToken(MUL, MUL, l.span(start)), nil
 
 	case l.ch == '/':
 		l.nextChar()
 		if l.ch == '=' {
 			l.nextChar()
-			return newToken(DIVASSIGN, DIVASSIGN, l.line, l.col, l.context), nil
+			return newToken(DIVASSIGN, DIVASSIGN, l.span(start)), nil
 		}
-		return newToken(DIV, DIV, l.line, l.col, l.context), nil
+		return newToken(DIV, DIV, l.span(start)), nil
 
 	case l.ch == '>':
 		l.nextChar()
@@ -96,12 +141,12 @@ func (l *Lexer) NextToken() (Token, error) {
 		switch l.ch {
 		case '>':
 			l.nextChar()
-			return newToken(BSR, BSR, l.line, l.col, l.context), nil
+			return newToken(BSR, BSR, l.span(start)), nil
 		case '=':
 			l.nextChar()
-			return newToken(GE, GE, l.line, l.col, l.context), nil
+			return newToken(GE, GE, l.span(start)), nil
 		default:
-			return newToken(GT, GT, l.line, l.col, l.context), nil
+			return newToken(GT, GT, l.span(start)), nil
 		}
 
 	case l.ch == '<':
@@ -109,12 +154,12 @@ func (l *Lexer) NextToken() (Token, error) {
 		switch l.ch {
 		case '<':
 			l.nextChar()
-			return newToken(BSL, BSL, l.line, l.col, l.context), nil
+			return newToken(BSL, BSL, l.span(start)), nil
 		case '=':
 			l.nextChar()
-			return newToken(LE, LE, l.line, l.col, l.context), nil
+			return newToken(LE, LE, l.span(start)), nil
 		default:
-			return newToken(LT, LT, l.line, l.col, l.context), nil
+			return newToken(LT, LT, l.span(start)), nil
 		}
 
 	case l.ch == '&':
@@ -122,12 +167,12 @@ func (l *Lexer) NextToken() (Token, error) {
 		switch l.ch {
 		case '&':
 			l.nextChar()
-			return newToken(LAND, LAND, l.line, l.col, l.context), nil
+			return newToken(LAND, LAND, l.span(start)), nil
 		case '=':
 			l.nextChar()
-			return newToken(BWAASSIGN, BWAASSIGN, l.line, l.col, l.context), nil
+			return newToken(BWAASSIGN, BWAASSIGN, l.span(start)), nil
 		default:
-			return newToken(BWAND, BWAND, l.line, l.col, l.context), nil
+			return newToken(BWAND, BWAND, l.span(start)), nil
 		}
 
 	case l.ch == '|':
@@ -135,166 +180,535 @@ func (l *Lexer) NextToken() (Token, error) {
 		switch l.ch {
 		case '|':
 			l.nextChar()
-			return newToken(LOR, LOR, l.line, l.col, l.context), nil
+			return newToken(LOR, LOR, l.span(start)), nil
 		case '=':
 			l.nextChar()
-			return newToken(BWOASSIGN, BWOASSIGN, l.line, l.col, l.context), nil
+			return newToken(BWOASSIGN, BWOASSIGN, l.span(start)), nil
 		default:
-			return newToken(BWOR, BWOR, l.line, l.col, l.context), nil
+			return newToken(BWOR, BWOR, l.span(start)), nil
 		}
 
 	case l.ch == '^':
 		l.nextChar()
 		if l.ch == '=' {
 			l.nextChar()
-			return newToken(BWNASSIGN, BWNASSIGN, l.line, l.col, l.context), nil
+			return newToken(BWNASSIGN, BWNASSIGN, l.span(start)), nil
 		}
-		return newToken(BWNOT, BWNOT, l.line, l.col, l.context), nil
+		return newToken(BWNOT, BWNOT, l.span(start)), nil
 
 	case l.ch == ',':
 		l.nextChar()
-		return newToken(COMMA, COMMA, l.line, l.col, l.context), nil
+		return newToken(COMMA, COMMA, l.span(start)), nil
 
 	case l.ch == ';':
 		l.nextChar()
-		return newToken(SEMICOL, SEMICOL, l.line, l.col, l.context), nil
+		return newToken(SEMICOL, SEMICOL, l.span(start)), nil
 
 	case l.ch == ':':
 		l.nextChar()
-		return newToken(COLON, COLON, l.line, l.col, l.context), nil
+		return newToken(COLON, COLON, l.span(start)), nil
 
 	case l.ch == '(':
 		l.nextChar()
-		return newToken(LPAREN, LPAREN, l.line, l.col, l.context), nil
+		return newToken(LPAREN, LPAREN, l.span(start)), nil
 
 	case l.ch == ')':
 		l.nextChar()
-		return newToken(RPAREN, RPAREN, l.line, l.col, l.context), nil
+		return newToken(RPAREN, RPAREN, l.span(start)), nil
 
 	case l.ch == '[':
 		l.nextChar()
-		return newToken(LSBRKT, LSBRKT, l.line, l.col, l.context), nil
+		return newToken(LSBRKT, LSBRKT, l.span(start)), nil
 
 	case l.ch == ']':
 		l.nextChar()
-		return newToken(RSBRKT, RSBRKT, l.line, l.col, l.context), nil
+		return newToken(RSBRKT, RSBRKT, l.span(start)), nil
 
 	case l.ch == '{':
 		l.nextChar()
-		return newToken(LBRACE, LBRACE, l.line, l.col, l.context), nil
+		if depth := len(l.interps); depth > 0 {
+			l.interps[depth-1]++
+		}
+		return newToken(LBRACE, LBRACE, l.span(start)), nil
 
 	case l.ch == '}':
+		if depth := len(l.interps); depth > 0 {
+			if l.interps[depth-1] == 0 {
+				// this closes an interpolation, so the string resumes
+				l.interps = l.interps[:depth-1]
+				return l.readStrLiteral(true)
+			}
+			l.interps[depth-1]--
+		}
 		l.nextChar()
-		return newToken(RBRACE, RBRACE, l.line, l.col, l.context), nil
+		return newToken(RBRACE, RBRACE, l.span(start)), nil
 
 	case l.ch == '.':
 		l.nextChar()
-		return newToken(DOT, DOT, l.line, l.col, l.context), nil
+		if l.ch == '.' {
+			l.nextChar()
+			switch l.ch {
+			case '=':
+				l.nextChar()
+				return newToken(INCLRANGE, INCLRANGE, l.span(start)), nil
+			case '.':
+				l.nextChar()
+				return newToken(ELLIPSIS, ELLIPSIS, l.span(start)), nil
+			}
+			return newToken(RANGE, RANGE, l.span(start)), nil
+		}
+		return newToken(DOT, DOT, l.span(start)), nil
 
 	case l.ch == '!':
 		l.nextChar()
 		if l.ch == '=' {
 			l.nextChar()
-			return newToken(NE, NE, l.line, l.col, l.context), nil
+			return newToken(NE, NE, l.span(start)), nil
 		}
-		return newToken(BANG, BANG, l.line, l.col, l.context), nil
+		return newToken(BANG, BANG, l.span(start)), nil
 
 	case l.ch == '"':
-		l.nextChar()
-		return l.readStrLiteral(), nil
+		return l.readStrLiteral(false)
+
+	case l.ch == '`':
+		return l.readRawStrLiteral()
 
 	case l.ch == 0:
-		return newToken(EOF, EOF, l.line, l.col, l.context), nil
+		if l.readErr != nil {
+			return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+				Msg: fmt.Sprintf("Could not read input: %s", l.readErr),
+				Con: start,
+			}
+		}
+		return newToken(EOF, EOF, l.span(start)), nil
 
 	default:
 		if isLetter(l.ch) {
 			return l.readIdent(), nil
 		}
 		if isNumber(l.ch) {
-			return l.readNumLit(), nil
+			return l.readNumLit()
 		}
-		return newToken("", "", 0, 0, ""), Err{
-			Msg: "Unknown token",
-			Con: newContext(l.line, l.col, l.context),
+		msg := fmt.Sprintf("Unknown token `%c`", l.ch)
+		if l.ch == utf8.RuneError {
+			msg = "Invalid UTF-8 encoding"
+		}
+		l.nextChar()
+		return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+			Msg: msg,
+			Con: start,
 		}
 	}
 }
 
 func (l *Lexer) readIdent() Token {
+	start := l.current()
 	position := l.pos
 	for isAlnum(l.ch) {
 		l.nextChar()
 	}
-	token := l.input[position:l.pos]
+	token := l.slice(position, l.pos)
 	if tok, isKw := lookupKeyword(token); isKw {
-		return newToken(tok, tok, l.line, l.col, l.context)
+		return newToken(tok, tok, l.span(start))
 	}
-	return newToken(IDENT, token, l.line, l.col, l.context)
+	return newToken(IDENT, token, l.span(start))
 }
 
-func (l *Lexer) readStrLiteral() Token {
-	position := l.pos
+// readStrLiteral reads a string literal starting at its opening quote, or
+// if resumed is set, the rest of an interpolated string starting at the
+// brace that closes an interpolation.
+//
+// Interpolated strings are split into several tokens: STRHEAD runs up to
+// the first ${, STRMID between each } and the next ${, and STRTAIL from
+// the last } to the closing quote. The tokens of each interpolated
+// expression are produced in between as usual.
+func (l *Lexer) readStrLiteral(resumed bool) (Token, error) {
+	start := l.current()
+	l.nextChar()
+
+	var out strings.Builder
 	for {
-		if l.ch == '"' {
-			token := l.input[position:l.pos]
+		switch l.ch {
+		case '"':
+			l.nextChar()
+			if resumed {
+				return newToken(STRTAIL, out.String(), l.span(start)), nil
+			}
+			return newToken(STRLIT, out.String(), l.span(start)), nil
+		case '$':
+			if l.peekChar() != '{' {
+				break
+			}
+			l.nextChar()
+			l.nextChar()
+			l.interps = append(l.interps, 0)
+			if resumed {
+				return newToken(STRMID, out.String(), l.span(start)), nil
+			}
+			return newToken(STRHEAD, out.String(), l.span(start)), nil
+		case '\\':
+			escape := l.current()
+			l.nextChar()
+			if msg := l.readEscape(&out); msg != "" {
+				l.skipStrLiteral()
+				return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+					Msg: msg,
+					Con: l.span(escape),
+				}
+			}
+			continue
+		case 0:
+			return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+				Msg: "Unterminated string literal",
+				Con: start,
+				EOF: true,
+			}
+		}
+		// copying the raw bytes keeps invalid UTF-8 intact
+		out.WriteString(l.slice(l.pos, l.readPos))
+		l.nextChar()
+	}
+}
+
+// readRawStrLiteral reads a backtick-delimited string literal. Raw strings
+// can span several lines and their contents are kept exactly as written,
+// with no escape sequences or interpolation.
+func (l *Lexer) readRawStrLiteral() (Token, error) {
+	start := l.current()
+	l.nextChar()
+
+	position := l.pos
+	for l.ch != '`' {
+		if l.ch == 0 {
+			return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+				Msg: "Unterminated raw string literal",
+				Con: start,
+				EOF: true,
+			}
+		}
+		l.nextChar()
+	}
+	literal := l.slice(position, l.pos)
+	l.nextChar()
+
+	return newToken(STRLIT, literal, l.span(start)), nil
+}
+
+// readEscape decodes the escape sequence following a backslash into out.
+// On success l.ch is the first character after the sequence, otherwise
+// the returned message describes what is wrong with it.
+func (l *Lexer) readEscape(out *strings.Builder) string {
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\':
+		out.WriteByte('\\')
+	case '"':
+		out.WriteByte('"')
+	case '$':
+		out.WriteByte('$')
+	case 'x':
+		// \xHH - only ASCII values are allowed, use \u{...} for the rest
+		l.nextChar()
+		hi, lo := hexValue(l.ch), -1
+		if hi >= 0 {
 			l.nextChar()
-			return newToken(STRLIT, token, l.line, l.col, l.context)
-		} else if l.ch == '\\' { //handling escaped characters and backslashes
+			lo = hexValue(l.ch)
+		}
+		if hi < 0 || lo < 0 {
+			return "Invalid escape sequence: expected two hex digits after \\x"
+		}
+		value := hi<<4 | lo
+		if value > 0x7f {
+			return fmt.Sprintf("Invalid escape sequence: \\x%02x is out of range (max \\x7f)", value)
+		}
+		out.WriteByte(byte(value))
+	case 'u':
+		l.nextChar()
+		if l.ch != '{' {
+			return "Invalid escape sequence: expected `{` after \\u"
+		}
+		l.nextChar()
+		value, digits := 0, 0
+		for ; hexValue(l.ch) >= 0; l.nextChar() {
+			value = value<<4 | hexValue(l.ch)
+			digits++
+			if digits > 6 {
+				return "Invalid escape sequence: \\u{...} takes at most 6 hex digits"
+			}
+		}
+		if l.ch != '}' || digits == 0 {
+			return "Invalid escape sequence: expected hex digits followed by `}` in \\u{...}"
+		}
+		if value > unicode.MaxRune || (0xd800 <= value && value <= 0xdfff) {
+			return fmt.Sprintf("Invalid escape sequence: \\u{%x} is not a valid character", value)
+		}
+		out.WriteRune(rune(value))
+	case 0:
+		return "Invalid escape sequence: unexpected end of input"
+	default:
+		return fmt.Sprintf("Invalid escape sequence: \\%c", l.ch)
+	}
+	l.nextChar()
+	return ""
+}
+
+// skipStrLiteral moves the lexer past the end of a string literal
+// that contains an error, so that lexing can resume after it
+func (l *Lexer) skipStrLiteral() {
+	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '\\' {
 			l.nextChar()
-			// switch l.ch {
-			// case 'n':
-			// 	l.input = strings.Replace(l.input, "\\n", "\n", 1)
-			// case 'r':
-			// 	l.input = strings.Replace(l.input, "\\r", "\r", 1)
-			// case 't':
-			// 	l.input = strings.Replace(l.input, "\\t", "\t", 1)
-			// default:
-			// 	l.input = strings.Replace(l.input, "\\", "", 1)
-			// 	l.col, l.context--
-			// }
-		} else if l.ch == 0 {
-			return newToken(EOF, EOF, l.line, l.col, l.context)
+			if l.ch == 0 {
+				return
+			}
 		}
 		l.nextChar()
 	}
+	l.nextChar()
 }
 
-func (l *Lexer) readNumLit() Token {
+func (l *Lexer) readNumLit() (Token, error) {
 	position := l.pos
+	start := l.current()
 	isFloat := false
-	for isNumber(l.ch) || l.ch == '_' || l.ch == '.' {
-		if l.ch == '.' {
-			isFloat = true
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.nextChar()
+		base := l.ch
+		if 'A' <= base && base <= 'Z' {
+			base += 'a' - 'A'
 		}
 		l.nextChar()
+		if err := l.readDigits(base, true); err != nil {
+			return l.illegalNumber(start), err
+		}
+	} else {
+		if err := l.readDigits('d', false); err != nil {
+			return l.illegalNumber(start), err
+		}
+		// a dot only starts a fraction if a digit follows it,
+		// so that 5.method() and 1..5 keep working
+		if l.ch == '.' && isNumber(l.peekChar()) {
+			isFloat = true
+			l.nextChar()
+			if err := l.readDigits('d', false); err != nil {
+				return l.illegalNumber(start), err
+			}
+			if l.ch == '.' && isNumber(l.peekChar()) {
+				err := l.numError("Invalid numeric literal: more than one decimal point")
+				return l.illegalNumber(start), err
+			}
+		}
+		if l.ch == 'e' || l.ch == 'E' {
+			isFloat = true
+			l.nextChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.nextChar()
+			}
+			if !isNumber(l.ch) {
+				err := l.numError("Invalid numeric literal: exponent has no digits")
+				return l.illegalNumber(start), err
+			}
+			if err := l.readDigits('d', false); err != nil {
+				return l.illegalNumber(start), err
+			}
+		}
 	}
-	token := l.input[position:l.pos]
+
+	if isLetter(l.ch) {
+		err := l.numError(fmt.Sprintf("Invalid character `%c` in numeric literal", l.ch))
+		return l.illegalNumber(start), err
+	}
+
+	token := l.slice(position, l.pos)
 	if isFloat {
-		return newToken(FLTLIT, token, l.line, l.col, l.context)
+		return newToken(FLTLIT, token, l.span(start)), nil
+	}
+	if hasLeadingZeros(token) {
+		return newToken(ILLEGAL, ILLEGAL, l.span(start)), Err{
+			Msg: "Invalid numeric literal: leading zeros are not allowed, use 0o for octal",
+			Con: start,
+		}
+	}
+	return newToken(INTLIT, token, l.span(start)), nil
+}
+
+// readDigits reads a run of digits in the given base ('b', 'o', 'x' or 'd'),
+// where single underscores may be used as separators between digits.
+// If afterPrefix is set, the run may start with a separator.
+func (l *Lexer) readDigits(base rune, afterPrefix bool) error {
+	count := 0
+	lastSep := afterPrefix
+	for {
+		switch {
+		case l.ch == '_':
+			if l.peekChar() == '_' {
+				return l.numError("Invalid numeric literal: consecutive `_` separators")
+			}
+			if count == 0 && !afterPrefix {
+				return l.numError("Invalid numeric literal: `_` must separate digits")
+			}
+			lastSep = true
+		case isDigitOf(l.ch, base):
+			count++
+			lastSep = false
+		case isAlnum(l.ch) && base != 'd':
+			return l.numError(fmt.Sprintf(
+				"Invalid digit `%c` in %s literal", l.ch, baseNames[base],
+			))
+		default:
+			if count == 0 {
+				return l.numError(fmt.Sprintf("Invalid numeric literal: %s literal has no digits", baseNames[base]))
+			}
+			if lastSep {
+				return l.numError("Invalid numeric literal: `_` must separate digits")
+			}
+			return nil
+		}
+		l.nextChar()
+	}
+}
+
+// numError returns an error for the character at the lexer's current position
+func (l *Lexer) numError(msg string) error {
+	return Err{Msg: msg, Con: l.current()}
+}
+
+// illegalNumber skips the rest of a malformed numeric literal
+func (l *Lexer) illegalNumber(start Context) Token {
+	for isAlnum(l.ch) || l.ch == '.' && isNumber(l.peekChar()) {
+		l.nextChar()
 	}
-	return newToken(INTLIT, token, l.line, l.col, l.context)
+	return newToken(ILLEGAL, ILLEGAL, l.span(start))
+}
+
+var baseNames = map[rune]string{
+	'b': "binary",
+	'o': "octal",
+	'x': "hexadecimal",
+	'd': "decimal",
 }
 
+// nextChar moves the lexer onto the next character in the input
 func (l *Lexer) nextChar() {
-	//have reached EOF
-	if l.readPos >= len(l.input) {
+	if l.pos == l.readPos {
+		// already at EOF
+		return
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.col = 1
+		l.context = l.lineAt(l.readPos)
+	} else {
+		l.col++
+	}
+	l.readChar()
+}
+
+// readChar decodes the character at readPos into ch
+func (l *Lexer) readChar() {
+	l.pos = l.readPos
+	if !l.buffered(l.readPos) {
 		l.ch = 0
-		if l.readPos == len(l.input) {
-			l.pos = l.readPos
+		return
+	}
+	ch, width := utf8.DecodeRuneInString(l.input[l.readPos-l.base:])
+	l.ch = ch
+	l.readPos += width
+}
+
+// lineAt returns the line of input starting at offset, without the newline
+func (l *Lexer) lineAt(offset int) string {
+	l.buffered(offset)
+	line := l.input[offset-l.base:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	return line
+}
+
+// slice returns the input between two offsets, both of which
+// must still be held in the buffer
+func (l *Lexer) slice(start int, end int) string {
+	return l.input[start-l.base : end-l.base]
+}
+
+// buffered reports whether the byte at offset is available,
+// reading more of the source into the buffer if needed
+func (l *Lexer) buffered(offset int) bool {
+	for offset-l.base >= len(l.input) {
+		if !l.fill() {
+			return false
 		}
-	} else {
-		l.ch = l.input[l.readPos]
-		l.pos = l.readPos
-		l.readPos++
-		if l.ch == '\n' {
-			l.col = 0
-			l.line++
-			l.context = l.input[l.lastln:l.pos]
-			l.lastln = l.pos
-		} else {
-			l.col++
+	}
+	return true
+}
+
+// fill reads the next line of the source into the buffer. Reading whole
+// lines keeps multi-byte characters and the current line's context intact.
+// It returns false once the source is exhausted.
+func (l *Lexer) fill() bool {
+	if l.reader == nil {
+		return false
+	}
+	line, err := l.reader.ReadString('\n')
+	if err != nil {
+		l.reader = nil
+		if err != io.EOF {
+			l.readErr = err
 		}
 	}
+	l.input += line
+	return line != ""
+}
+
+// discard drops the text before the current character from the buffer,
+// so that lexing from a reader only holds on to the text it needs
+func (l *Lexer) discard() {
+	if l.reader == nil || l.pos-l.base < discardSize {
+		return
+	}
+	l.input = l.input[l.pos-l.base:]
+	l.base = l.pos
+}
+
+// discardSize is how much consumed input a reader lexer buffers before discarding it
+const discardSize = 4096
+
+// current returns the lexer's current position as an empty range
+func (l *Lexer) current() Context {
+	return Context{
+		Line:   l.line,
+		Col:    l.col,
+		Ctxt:   l.context,
+		Offset: l.pos,
+		End:    l.position(),
+	}
+}
+
+// span returns a range from start up to the lexer's current position
+func (l *Lexer) span(start Context) Context {
+	start.End = l.position()
+	return start
+}
+
+func (l *Lexer) position() Position {
+	return Position{Line: l.line, Col: l.col, Offset: l.pos}
+}
+
+func (l *Lexer) peekChar() rune {
+	if !l.buffered(l.readPos) {
+		return 0
+	}
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPos-l.base:])
+	return ch
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -303,25 +717,51 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
-func (l *Lexer) skipBlockComment() {
+// skipComments skips over any whitespace and comments before the next token
+func (l *Lexer) skipComments() error {
 	for {
-		l.nextChar()
-		if l.ch == '*' {
+		l.skipWhitespace()
+		switch {
+		case l.ch == '/' && l.peekChar() == '/':
+			l.skipLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			if err := l.skipBlockComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// skipBlockComment skips a block comment, which may contain nested
+// block comments. l.ch must be the opening slash.
+func (l *Lexer) skipBlockComment() error {
+	start := l.current()
+	depth := 0
+	for {
+		switch {
+		case l.ch == 0:
+			return Err{Msg: "Unterminated block comment", Con: start, EOF: true}
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
 			l.nextChar()
-			if l.ch == '/' {
-				break
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.nextChar()
+			if depth == 0 {
+				l.nextChar()
+				return nil
 			}
-			continue
 		}
+		l.nextChar()
 	}
 }
 
+// skipLineComment skips to the end of the line, leaving the newline
 func (l *Lexer) skipLineComment() {
-	for {
+	for l.ch != '\n' && l.ch != 0 {
 		l.nextChar()
-		if l.ch == '\n' {
-			break
-		}
 	}
 }
 
@@ -332,24 +772,76 @@ func lookupKeyword(ident string) (string, bool) {
 	return ident, false
 }
 
-func isAlnum(ch byte) bool {
+func isAlnum(ch rune) bool {
 	return isLetter(ch) || isNumber(ch)
 }
 
-func isLetter(ch byte) bool {
+func isLetter(ch rune) bool {
 	return 'a' <= ch && ch <= 'z' ||
 		'A' <= ch && ch <= 'Z' ||
-		ch == '_'
+		ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isNumber(ch byte) bool {
+// isNumber only accepts ASCII digits, other Unicode digits
+// are not valid in numeric literals or identifiers
+func isNumber(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	default:
+		return false
+	}
+}
+
+// hasLeadingZeros reports whether a decimal integer literal starts with
+// a zero that Go's number parsing would treat as an octal prefix
+func hasLeadingZeros(lit string) bool {
+	if len(lit) < 2 || lit[0] != '0' || isBasePrefix(rune(lit[1])) {
+		return false
+	}
+	return strings.Trim(lit, "0_") != ""
+}
+
+// isDigitOf reports whether ch is a valid digit in the given base
+func isDigitOf(ch rune, base rune) bool {
+	switch base {
+	case 'b':
+		return ch == '0' || ch == '1'
+	case 'o':
+		return '0' <= ch && ch <= '7'
+	case 'x':
+		return hexValue(ch) >= 0
+	default:
+		return isNumber(ch)
+	}
+}
+
+// hexValue returns the value of a hex digit, or -1 if ch is not one
+func hexValue(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'F':
+		return int(ch-'A') + 10
+	default:
+		return -1
+	}
+}
+
 // Err represents the an error that the lexer can return
 type Err struct {
 	Msg string
 	Con Context
+	// EOF is set when the error is caused by the input ending
+	// before a string or comment was closed
+	EOF bool
 }
 
 func (err Err) Error() string {
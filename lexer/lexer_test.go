@@ -1,6 +1,13 @@
 package lexer
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
 
 func TestNextToken(t *testing.T) {
 
@@ -80,7 +87,7 @@ if thou == 1_000.57 {
 		{LET, "let"},
 		{IDENT, "str"},
 		{ASSIGN, "="},
-		{STRLIT, "Hello i am\\n cool\\n"},
+		{STRLIT, "Hello i am\n cool\n"},
 		{SEMICOL, ";"},
 		{LET, "let"},
 		{IDENT, "add"},
@@ -123,3 +130,532 @@ if thou == 1_000.57 {
 		}
 	}
 }
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there\r"`, "tab\there\r"},
+		{`"\\ and \""`, "\\ and \""},
+		{`"nul\0"`, "nul\x00"},
+		{`"\x41\x7e"`, "A~"},
+		{`"\u{48}\u{e9}\u{1F600}"`, "H\u00e9\U0001F600"},
+	}
+
+	for i, tt := range tests {
+		tok, err := New(tt.input).NextToken()
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+			continue
+		}
+		if tok.Type != STRLIT || tok.Literal != tt.expected {
+			t.Errorf("test %d: expected STRLIT %q, got %s %q", i, tt.expected, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		col   int
+	}{
+		{`let s = "abc\q";`, 13},
+		{`let s = "\x4";`, 10},
+		{`let s = "\x80";`, 10},
+		{`let s = "\u{110000}";`, 10},
+		{`let s = "\u{d800}";`, 10},
+		{`let s = "\u41";`, 10},
+		{`let s = "abc`, 9},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		var err error
+		for tok := (Token{}); tok.Type != EOF && err == nil; {
+			tok, err = l.NextToken()
+		}
+		lexErr, ok := err.(Err)
+		if !ok {
+			t.Errorf("test %d: expected lexer error, got %v", i, err)
+			continue
+		}
+		if lexErr.Con.Line != 1 || lexErr.Con.Col != tt.col {
+			t.Errorf("test %d: expected error at 1:%d, got %d:%d (%s)",
+				i, tt.col, lexErr.Con.Line, lexErr.Con.Col, lexErr.Msg)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"hi ${name}, ${len(items) + {"a": 1}["a"]} ${"in${x}"}!"`
+
+	tests := []struct {
+		expectedType    string
+		expectedLiteral string
+	}{
+		{STRHEAD, "hi "},
+		{IDENT, "name"},
+		{STRMID, ", "},
+		{IDENT, "len"},
+		{LPAREN, "("},
+		{IDENT, "items"},
+		{RPAREN, ")"},
+		{ADD, "+"},
+		{LBRACE, "{"},
+		{STRLIT, "a"},
+		{COLON, ":"},
+		{INTLIT, "1"},
+		{RBRACE, "}"},
+		{LSBRKT, "["},
+		{STRLIT, "a"},
+		{RSBRKT, "]"},
+		{STRMID, " "},
+		{STRHEAD, "in"},
+		{IDENT, "x"},
+		{STRTAIL, ""},
+		{STRTAIL, "!"},
+		{EOF, "EOF"},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("test %d: unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test %d: expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	input := "let re = `^\\d+\\s*${x}\\n$`;\nlet sql = `SELECT *\n  FROM \"t\"\n`; done"
+
+	tests := []struct {
+		expectedType    string
+		expectedLiteral string
+		start           Position
+	}{
+		{LET, LET, Position{1, 1, 0}},
+		{IDENT, "re", Position{1, 5, 4}},
+		{ASSIGN, ASSIGN, Position{1, 8, 7}},
+		{STRLIT, `^\d+\s*${x}\n$`, Position{1, 10, 9}},
+		{SEMICOL, SEMICOL, Position{1, 26, 25}},
+		{LET, LET, Position{2, 1, 27}},
+		{IDENT, "sql", Position{2, 5, 31}},
+		{ASSIGN, ASSIGN, Position{2, 9, 35}},
+		{STRLIT, "SELECT *\n  FROM \"t\"\n", Position{2, 11, 37}},
+		{SEMICOL, SEMICOL, Position{4, 2, 59}},
+		{IDENT, "done", Position{4, 4, 61}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("token %d: unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("token %d: expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+		if start := tok.Pos.Start(); start != tt.start {
+			t.Errorf("token %d (%s): expected start %+v, got %+v", i, tok.Type, tt.start, start)
+		}
+	}
+
+	_, err := New("let s = `abc\ndef").Tokenize()
+	lexErr, ok := err.(Err)
+	if !ok {
+		t.Fatalf("expected lexer error for unterminated raw string, got %v", err)
+	}
+	if lexErr.Con.Line != 1 || lexErr.Con.Col != 9 {
+		t.Errorf("expected error at 1:9, got %d:%d", lexErr.Con.Line, lexErr.Con.Col)
+	}
+}
+
+func TestNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType string
+		expectedLit  string
+	}{
+		{"0x1F", INTLIT, "0x1F"},
+		{"0XdEaD_bEeF", INTLIT, "0XdEaD_bEeF"},
+		{"0o755", INTLIT, "0o755"},
+		{"0b1010_0101", INTLIT, "0b1010_0101"},
+		{"0", INTLIT, "0"},
+		{"1_000_000", INTLIT, "1_000_000"},
+		{"1.5e-3", FLTLIT, "1.5e-3"},
+		{"2E+10", FLTLIT, "2E+10"},
+		{"6e2", FLTLIT, "6e2"},
+		{"0.25", FLTLIT, "0.25"},
+		{"1..5", INTLIT, "1"},
+		{"5.len()", INTLIT, "5"},
+	}
+
+	for i, tt := range tests {
+		tok, err := New(tt.input).NextToken()
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+			continue
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLit {
+			t.Errorf("test %d: expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLit, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestInvalidNumericLiterals(t *testing.T) {
+	tests := []string{
+		"1__0",
+		"1_",
+		"1_.5",
+		"1.2.3",
+		"0x",
+		"0xfg",
+		"0b102",
+		"0o8",
+		"1e",
+		"1e+_5",
+		"123abc",
+		"007",
+	}
+
+	for i, input := range tests {
+		l := New(input)
+		tok, err := l.NextToken()
+		if err == nil {
+			t.Errorf("test %d: expected error for %q, got %s %q", i, input, tok.Type, tok.Literal)
+			continue
+		}
+		if tok.Type != ILLEGAL {
+			t.Errorf("test %d: expected ILLEGAL token, got %s", i, tok.Type)
+		}
+		// the rest of the malformed literal must be skipped
+		if tok, err = l.NextToken(); err != nil || tok.Type != EOF {
+			t.Errorf("test %d: expected EOF after %q, got %s %v", i, input, tok.Type, err)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a line comment
+let x = 10 / 2; // trailing comment
+/* a block
+   comment */ let y /* inline */ = x;
+/* outer /* nested */ still a comment */
+x /= y;
+// comment at end of input`
+
+	expected := []string{
+		LET, IDENT, ASSIGN, INTLIT, DIV, INTLIT, SEMICOL,
+		LET, IDENT, ASSIGN, IDENT, SEMICOL,
+		IDENT, DIVASSIGN, IDENT, SEMICOL,
+		EOF,
+	}
+
+	l := New(input)
+	for i, tt := range expected {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("token %d: unexpected error: %s", i, err)
+		}
+		if tok.Type != tt {
+			t.Fatalf("token %d: expected %q, got %q (%q)", i, tt, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	l := New("let x = 5;\n  /* outer /* inner */ never closed")
+	var err error
+	for tok := (Token{}); tok.Type != EOF && err == nil; {
+		tok, err = l.NextToken()
+	}
+	lexErr, ok := err.(Err)
+	if !ok {
+		t.Fatalf("expected lexer error, got %v", err)
+	}
+	if lexErr.Con.Line != 2 || lexErr.Con.Col != 3 {
+		t.Errorf("expected error at 2:3, got %d:%d", lexErr.Con.Line, lexErr.Con.Col)
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let größe = "héllo, 世界";
+let 名前 = größe; 🦝`
+
+	tests := []struct {
+		expectedType string
+		expectedLit  string
+		col          int
+	}{
+		{LET, "let", 1},
+		{IDENT, "größe", 5},
+		{ASSIGN, "=", 11},
+		{STRLIT, "héllo, 世界", 13},
+		{SEMICOL, ";", 24},
+		{LET, "let", 1},
+		{IDENT, "名前", 5},
+		{ASSIGN, "=", 8},
+		{IDENT, "größe", 10},
+		{SEMICOL, ";", 15},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("token %d: unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLit {
+			t.Fatalf("token %d: expected %s %q, got %s %q",
+				i, tt.expectedType, tt.expectedLit, tok.Type, tok.Literal)
+		}
+		if tok.Pos.Col != tt.col {
+			t.Errorf("token %d: expected column %d, got %d", i, tt.col, tok.Pos.Col)
+		}
+	}
+
+	// emoji are not letters, so cannot be used in identifiers
+	if _, err := l.NextToken(); err == nil {
+		t.Errorf("expected error for non-letter character")
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = \"é\";\n\tfoo(x) /* c */ >= 10\n"
+
+	tests := []struct {
+		expectedType string
+		start        Position
+		end          Position
+	}{
+		{LET, Position{1, 1, 0}, Position{1, 4, 3}},
+		{IDENT, Position{1, 5, 4}, Position{1, 6, 5}},
+		{ASSIGN, Position{1, 7, 6}, Position{1, 8, 7}},
+		{STRLIT, Position{1, 9, 8}, Position{1, 12, 12}},
+		{SEMICOL, Position{1, 12, 12}, Position{1, 13, 13}},
+		{IDENT, Position{2, 2, 15}, Position{2, 5, 18}},
+		{LPAREN, Position{2, 5, 18}, Position{2, 6, 19}},
+		{IDENT, Position{2, 6, 19}, Position{2, 7, 20}},
+		{RPAREN, Position{2, 7, 20}, Position{2, 8, 21}},
+		{GE, Position{2, 17, 30}, Position{2, 19, 32}},
+		{INTLIT, Position{2, 20, 33}, Position{2, 22, 35}},
+		{EOF, Position{3, 1, 36}, Position{3, 1, 36}},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatalf("token %d: unexpected error: %s", i, err)
+		}
+		if tok.Type != tt.expectedType {
+			t.Fatalf("token %d: expected %s, got %s", i, tt.expectedType, tok.Type)
+		}
+		if start := tok.Pos.Start(); start != tt.start {
+			t.Errorf("token %d (%s): expected start %+v, got %+v", i, tok.Type, tt.start, start)
+		}
+		if tok.Pos.End != tt.end {
+			t.Errorf("token %d (%s): expected end %+v, got %+v", i, tok.Type, tt.end, tok.Pos.End)
+		}
+		if tok.Type != EOF && input[tok.Pos.Offset:tok.Pos.End.Offset] == "" {
+			t.Errorf("token %d (%s): empty source range", i, tok.Type)
+		}
+	}
+
+	l = New("a\n  b")
+	l.NextToken()
+	if tok, _ := l.NextToken(); tok.Pos.Ctxt != "  b" {
+		t.Errorf("expected line context %q, got %q", "  b", tok.Pos.Ctxt)
+	}
+}
+
+func TestReaderMatchesString(t *testing.T) {
+	input := `let größe = 0x1F; // comment
+/* block
+   comment */ let s = "multi
+line \u{1F600} string";
+fn add(a, b) { return a + b; }
+` + strings.Repeat("let padding = [1, 2.5, \"three\"];\n", 400) + "add(größe, 2)"
+
+	// one byte at a time forces lines to be split across reads
+	readers := map[string]*Lexer{
+		"reader":   NewReader(strings.NewReader(input)),
+		"one byte": NewReader(iotest.OneByteReader(strings.NewReader(input))),
+	}
+
+	for name, rl := range readers {
+		sl := New(input)
+		for i := 0; ; i++ {
+			expected, experr := sl.NextToken()
+			actual, err := rl.NextToken()
+			if experr != nil || err != nil {
+				t.Fatalf("%s: token %d: unexpected error: %v, %v", name, i, experr, err)
+			}
+			if actual != expected {
+				t.Fatalf("%s: token %d: expected %+v, got %+v", name, i, expected, actual)
+			}
+			if expected.Type == EOF {
+				break
+			}
+		}
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	failure := errors.New("disk on fire")
+	l := NewReader(io.MultiReader(strings.NewReader("let x = 1;\nlet"), iotest.ErrReader(failure)))
+
+	var err error
+	for tok := (Token{}); tok.Type != EOF && err == nil; {
+		tok, err = l.NextToken()
+	}
+	if err == nil || !strings.Contains(err.Error(), failure.Error()) {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tokens, err := New("let x = 5;").Tokenize()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{LET, IDENT, ASSIGN, INTLIT, SEMICOL}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens, got %d: %+v", len(expected), len(tokens), tokens)
+	}
+	for i, tok := range tokens {
+		if tok.Type != expected[i] {
+			t.Errorf("token %d: expected %s, got %s", i, expected[i], tok.Type)
+		}
+	}
+
+	tokens, err = New("let x = 08;").Tokenize()
+	if err == nil {
+		t.Errorf("expected an error for an invalid literal")
+	}
+	if len(tokens) != 3 {
+		t.Errorf("expected the 3 tokens before the error, got %d", len(tokens))
+	}
+}
+
+func TestRangeTokens(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"[1..5]", []string{LSBRKT, INTLIT, RANGE, INTLIT, RSBRKT}},
+		{"[0..=10:2]", []string{LSBRKT, INTLIT, INCLRANGE, INTLIT, COLON, INTLIT, RSBRKT}},
+		{"xs[..n]", []string{IDENT, LSBRKT, RANGE, IDENT, RSBRKT}},
+		{"1.5..x.y", []string{FLTLIT, RANGE, IDENT, DOT, IDENT}},
+		{"[a, ...b]", []string{LSBRKT, IDENT, COMMA, ELLIPSIS, IDENT, RSBRKT}},
+	}
+
+	for i, tt := range tests {
+		tokens, err := New(tt.input).Tokenize()
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %s", i, err)
+			continue
+		}
+		types := []string{}
+		for _, tok := range tokens {
+			types = append(types, tok.Type)
+		}
+		if !reflect.DeepEqual(types, tt.expected) {
+			t.Errorf("test %d: expected %v, got %v", i, tt.expected, types)
+		}
+	}
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"   \n\t",
+		"let x = 5;",
+		"  // leading comment\nlet x = 0x1F; // trailing comment\n\n  /* a /* nested */ block */ x",
+		"let s = \"esc\\n\\u{1F600}\"; let r = `raw\n  ${x}`;\r\nlet i = \"a ${ {\"k\": b}[\"k\"] } c\";\n",
+		"fn größe(a, b) {\n\treturn a + b; /* multi\nline */ }\n// no newline at end",
+	}
+
+	for i, input := range inputs {
+		for name, l := range map[string]*Lexer{
+			"string": New(input),
+			"reader": NewReader(iotest.OneByteReader(strings.NewReader(input))),
+		} {
+			tokens, err := l.TokenizeTrivia()
+			if err != nil {
+				t.Errorf("input %d (%s): unexpected error: %s", i, name, err)
+				continue
+			}
+			if last := tokens[len(tokens)-1]; last.Type != EOF {
+				t.Errorf("input %d (%s): expected stream to end with EOF, got %s", i, name, last.Type)
+			}
+
+			var out strings.Builder
+			for _, tok := range tokens {
+				out.WriteString(tok.String())
+			}
+			if out.String() != input {
+				t.Errorf("input %d (%s): expected %q, got %q", i, name, input, out.String())
+			}
+		}
+	}
+}
+
+func TestTriviaAttachment(t *testing.T) {
+	input := "// header\n\nlet x = 1; // one\n  /* two */ x\n"
+
+	tests := []struct {
+		expectedType string
+		leading      []string
+		trailing     []string
+	}{
+		{LET, []string{LINECOMMENT, NEWLINE, NEWLINE}, []string{WHITESPACE}},
+		{IDENT, nil, []string{WHITESPACE}},
+		{ASSIGN, nil, []string{WHITESPACE}},
+		{INTLIT, nil, nil},
+		{SEMICOL, nil, []string{WHITESPACE, LINECOMMENT, NEWLINE}},
+		{IDENT, []string{WHITESPACE, BLOCKCOMMENT, WHITESPACE}, []string{NEWLINE}},
+		{EOF, nil, nil},
+	}
+
+	tokens, err := New(input).TokenizeTrivia()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tokens) != len(tests) {
+		t.Fatalf("expected %d tokens, got %d", len(tests), len(tokens))
+	}
+
+	kinds := func(trivia []Trivia) (out []string) {
+		for _, tr := range trivia {
+			out = append(out, tr.Type)
+		}
+		return out
+	}
+	for i, tt := range tests {
+		tok := tokens[i]
+		if tok.Type != tt.expectedType {
+			t.Fatalf("token %d: expected %s, got %s", i, tt.expectedType, tok.Type)
+		}
+		if leading := kinds(tok.Leading); !reflect.DeepEqual(leading, tt.leading) {
+			t.Errorf("token %d (%s): expected leading %v, got %v", i, tok.Type, tt.leading, leading)
+		}
+		if trailing := kinds(tok.Trailing); !reflect.DeepEqual(trailing, tt.trailing) {
+			t.Errorf("token %d (%s): expected trailing %v, got %v", i, tok.Type, tt.trailing, trailing)
+		}
+	}
+
+	if raw := tokens[5].Leading[1]; raw.Text != "/* two */" || raw.Pos.Line != 4 || raw.Pos.Col != 3 {
+		t.Errorf("expected block comment at 4:3, got %q at %d:%d", raw.Text, raw.Pos.Line, raw.Pos.Col)
+	}
+}
@@ -1,10 +1,35 @@
 package lexer
 
-// Context is the place of that particular token in the text
-type Context struct {
+// Position is a single point in the source text
+type Position struct {
 	Line int
 	Col  int
-	Ctxt string
+	// Offset is the byte offset of the point from the start of the input
+	Offset int
+}
+
+// Context is the place of that particular token in the text
+//
+// Line, Col and Offset give the start of the range it covers,
+// and End is the position just after its last character.
+// Ctxt is the text of the line that the range starts on.
+type Context struct {
+	Line   int
+	Col    int
+	Ctxt   string
+	Offset int
+	End    Position
+}
+
+// Start returns the position that the range begins at
+func (c Context) Start() Position {
+	return Position{Line: c.Line, Col: c.Col, Offset: c.Offset}
+}
+
+// Span returns a range running from the start of from to the end of to
+func Span(from Context, to Context) Context {
+	from.End = to.End
+	return from
 }
 
 // Token represents a single word in Monkey
@@ -14,17 +39,11 @@ type Token struct {
 	Pos     Context
 }
 
-func newToken(
-	ttype string,
-	lit string,
-	line int,
-	col int,
-	ctx string,
-) Token {
+func newToken(ttype string, lit string, pos Context) Token {
 	return Token{
 		Type:    ttype,
 		Literal: lit,
-		Pos:     newContext(line, col, ctx),
+		Pos:     pos,
 	}
 }
 
@@ -32,14 +51,6 @@ func (tok *Token) isEOF() bool {
 	return tok.Type == EOF
 }
 
-func newContext(line int, col int, ctx string) Context {
-	return Context{
-		Line: line,
-		Col:  col,
-		Ctxt: ctx,
-	}
-}
-
 //Types of tokens
 const (
 	// ILLEGAL - Unknown token
@@ -58,6 +69,12 @@ const (
 	INTLIT = "INTLIT"
 	// FLTLIT - Float literal
 	FLTLIT = "FLTLIT"
+	// STRHEAD - Start of an interpolated string, up to the first ${
+	STRHEAD = "STRHEAD"
+	// STRMID - Text between two interpolations, from } to ${
+	STRMID = "STRMID"
+	// STRTAIL - End of an interpolated string, from } to the closing quote
+	STRTAIL = "STRTAIL"
 
 	//Operators
 
@@ -86,6 +103,11 @@ const (
 
 	DOT = "."
 
+	RANGE     = ".."
+	INCLRANGE = "..="
+	// ELLIPSIS marks a rest pattern, such as [head, ...tail]
+	ELLIPSIS = "..."
+
 	LT = "<"
 	GT = ">"
 	LE = "<="
@@ -114,40 +136,52 @@ const (
 	//Keywords
 
 	FUNCTION = "fn"
+	IMPORT   = "import"
 	LET      = "let"
 	RETURN   = "return"
 	RETSIG   = "->"
+	// FATARROW separates the pattern of a match arm from its body
+	FATARROW = "=>"
 	IF       = "if"
 	ELSE     = "else"
 	WHILE    = "while"
 	FOR      = "for"
 	BREAK    = "break"
+	CONTINUE = "continue"
 	IN       = "in"
 	LOOP     = "loop"
 	STRING   = "str"
 	INT      = "int"
 	FLOAT    = "flt"
 	CLASS    = "class"
+	TRAIT    = "trait"
+	IMPL     = "impl"
+	MATCH    = "match"
 	BOOL     = "bool"
 	TRUE     = "true"
 	FALSE    = "false"
 )
 
 var keywords = map[string]string{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"return": RETURN,
-	"if":     IF,
-	"else":   ELSE,
-	"while":  WHILE,
-	"for":    FOR,
-	"break":  BREAK,
-	"in":     IN,
-	"loop":   LOOP,
-	"int":    INT,
-	"float":  FLOAT,
-	"class":  CLASS,
-	"bool":   BOOL,
-	"true":   TRUE,
-	"false":  FALSE,
+	"fn":       FUNCTION,
+	"import":   IMPORT,
+	"let":      LET,
+	"return":   RETURN,
+	"if":       IF,
+	"else":     ELSE,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"in":       IN,
+	"loop":     LOOP,
+	"int":      INT,
+	"float":    FLOAT,
+	"class":    CLASS,
+	"trait":    TRAIT,
+	"impl":     IMPL,
+	"match":    MATCH,
+	"bool":     BOOL,
+	"true":     TRUE,
+	"false":    FALSE,
 }
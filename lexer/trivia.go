@@ -0,0 +1,133 @@
+package lexer
+
+import "strings"
+
+// Kinds of trivia
+const (
+	// WHITESPACE - Spaces, tabs and carriage returns
+	WHITESPACE = "WHITESPACE"
+	// NEWLINE - A single line feed
+	NEWLINE = "NEWLINE"
+	// LINECOMMENT - A // comment, not including its newline
+	LINECOMMENT = "LINECOMMENT"
+	// BLOCKCOMMENT - A /* */ comment, including any nested comments
+	BLOCKCOMMENT = "BLOCKCOMMENT"
+)
+
+// Trivia is a piece of source text between tokens that does not
+// affect the meaning of the program
+type Trivia struct {
+	Type string
+	Text string
+	Pos  Context
+}
+
+// TriviaToken is a token together with the trivia around it
+//
+// Trailing trivia runs from the end of the token up to and including the
+// first newline after it, and everything else before a token is leading
+// trivia. Joining the leading trivia, Raw and the trailing trivia of every
+// token in order, up to and including the EOF, reproduces the source exactly.
+type TriviaToken struct {
+	Token
+	// Raw is the token's text as it appears in the source,
+	// before escapes are decoded
+	Raw      string
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// String returns the source text covered by the token and its trivia
+func (tt TriviaToken) String() string {
+	var out strings.Builder
+
+	for _, trivia := range tt.Leading {
+		out.WriteString(trivia.Text)
+	}
+	out.WriteString(tt.Raw)
+	for _, trivia := range tt.Trailing {
+		out.WriteString(trivia.Text)
+	}
+
+	return out.String()
+}
+
+// TokenizeTrivia fully advances the lexer and returns every token with
+// its trivia attached, including the EOF, which holds the trivia at the
+// end of the input. It stops at the first error it encounters.
+func (l *Lexer) TokenizeTrivia() (tokens []TriviaToken, err error) {
+	tokens = make([]TriviaToken, 0)
+	for {
+		tok, err := l.NextTriviaToken()
+		tokens = append(tokens, tok)
+		if err != nil {
+			return tokens, err
+		}
+		if tok.isEOF() {
+			return tokens, nil
+		}
+	}
+}
+
+// NextTriviaToken advances the lexer and produces a token along with its
+// leading and trailing trivia. If an error is returned, the token holds
+// whatever was consumed before it occurred, so that the source text is
+// still accounted for.
+func (l *Lexer) NextTriviaToken() (TriviaToken, error) {
+	leading, err := l.readTrivia(false)
+	if err != nil {
+		return TriviaToken{Token: newToken(ILLEGAL, ILLEGAL, l.current()), Leading: leading}, err
+	}
+
+	tok, err := l.NextToken()
+	tt := TriviaToken{
+		Token:   tok,
+		Raw:     l.slice(tok.Pos.Offset, tok.Pos.End.Offset),
+		Leading: leading,
+	}
+	if err != nil || tok.isEOF() {
+		return tt, err
+	}
+
+	tt.Trailing, err = l.readTrivia(true)
+	return tt, err
+}
+
+// readTrivia consumes the trivia before the next token. If trailing is set,
+// it stops after the first newline.
+func (l *Lexer) readTrivia(trailing bool) ([]Trivia, error) {
+	var trivia []Trivia
+	for {
+		start := l.current()
+		var kind string
+		var err error
+
+		switch {
+		case l.ch == ' ' || l.ch == '\t' || l.ch == '\r':
+			kind = WHITESPACE
+			for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
+				l.nextChar()
+			}
+		case l.ch == '\n':
+			kind = NEWLINE
+			l.nextChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			kind = LINECOMMENT
+			l.skipLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			kind = BLOCKCOMMENT
+			err = l.skipBlockComment()
+		default:
+			return trivia, nil
+		}
+
+		trivia = append(trivia, Trivia{
+			Type: kind,
+			Text: l.slice(start.Offset, l.pos),
+			Pos:  l.span(start),
+		})
+		if err != nil || (trailing && kind == NEWLINE) {
+			return trivia, err
+		}
+	}
+}
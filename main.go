@@ -1,13 +1,21 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/user"
 
+	"github.com/cartoon-raccoon/lemur/eval"
+	"github.com/cartoon-raccoon/lemur/object"
 	"github.com/cartoon-raccoon/lemur/repl"
 )
 
 func main() {
+	// a file given on the command line is run instead of starting the repl
+	if len(os.Args) > 1 {
+		os.Exit(runFile(os.Args[1]))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,3 +23,17 @@ func main() {
 	r := repl.New()
 	r.Run(user.Username, os.Stdin, os.Stdout)
 }
+
+// runFile evaluates the file at path and returns the exit status
+func runFile(path string) int {
+	e := eval.New()
+	res := e.EvaluateFile(path, object.NewEnv())
+	for _, warning := range e.Warnings() {
+		fmt.Fprintf(os.Stdout, "warning: %s\n", warning.Error())
+	}
+	if object.IsErr(res) {
+		fmt.Fprintf(os.Stderr, "%s\n", res.Inspect())
+		return 1
+	}
+	return 0
+}
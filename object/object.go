@@ -2,6 +2,7 @@ package object
 
 import (
 	"bytes"
+	"encoding/binary"
 	"fmt"
 	"hash/fnv"
 	"strings"
@@ -21,8 +22,14 @@ const (
 	BOOLEAN = "BOOL_OBJ"
 	//ARRAY - Array
 	ARRAY = "ARR_OBJ"
+	//TUPLE - Tuple
+	TUPLE = "TUPLE_OBJ"
 	//MAP - Map
 	MAP = "MAP_OBJ"
+	//SET - Set
+	SET = "SET_OBJ"
+	//RANGE - Range of integers
+	RANGE = "RANGE_OBJ"
 	//INDEX - Map or Array index
 	INDEX = "IDX_OBJ"
 	//NULL - Null value
@@ -31,10 +38,24 @@ const (
 	RETURN = "RETURN_OBJ"
 	//BREAK - A break value
 	BREAK = "BRK_OBJ"
+	//CONTINUE - A continue value
+	CONTINUE = "CONT_OBJ"
+	//ITERATOR - Iterator over a collection
+	ITERATOR = "ITER_OBJ"
 	//FUNCTION - Function object
 	FUNCTION = "FUNC_OBJ"
 	//BUILTIN - Builtin function
 	BUILTIN = "BUILTIN_OBJ"
+	//MODULE - An imported module
+	MODULE = "MOD_OBJ"
+	//CLASS - A class declaration
+	CLASS = "CLASS_OBJ"
+	//INSTANCE - An instance of a class
+	INSTANCE = "INST_OBJ"
+	//METHOD - A method bound to an instance
+	METHOD = "METHOD_OBJ"
+	//TRAIT - A trait declaration
+	TRAIT = "TRAIT_OBJ"
 
 	//ERROR - Error object
 	ERROR = "ERROR_OBJ"
@@ -94,6 +115,19 @@ func (env *Environment) Set(ident string, val Object) Object {
 	return val
 }
 
+// Assign rebinds a variable in the innermost environment that declares it.
+// It returns false if the variable has not been declared.
+func (env *Environment) Assign(ident string, val Object) bool {
+	if _, ok := env.Data[ident]; ok {
+		env.Data[ident] = val
+		return true
+	}
+	if env.Outer != nil {
+		return env.Outer.Assign(ident, val)
+	}
+	return false
+}
+
 // Type implements Object for Environment
 func (env *Environment) Type() string { return ENVIRONMENT }
 
@@ -209,9 +243,76 @@ func (a *Array) Display() {
 	fmt.Println(a.Inspect())
 }
 
+// Tuple represents a fixed sequence of values, which cannot be changed
+type Tuple struct {
+	Elements []Object
+}
+
+// Type implements Object for Tuple
+func (t *Tuple) Type() string { return TUPLE }
+
+// Inspect implements Object for Tuple
+func (t *Tuple) Inspect() string {
+	elems := []string{}
+	for _, elem := range t.Elements {
+		elems = append(elems, elem.Inspect())
+	}
+	if len(elems) == 1 {
+		return "(" + elems[0] + ",)"
+	}
+	return "(" + strings.Join(elems, ", ") + ")"
+}
+
+// Display implements Object for Tuple
+func (t *Tuple) Display() {
+	fmt.Println(t.Inspect())
+}
+
 // Map represents a map in memory
 type Map struct {
-	Elements map[HashKey]Object
+	Elements map[HashKey]MapPair
+	// Order holds the keys in the order they were first inserted
+	Order []HashKey
+}
+
+// MapPair is a key in a Map along with its value
+type MapPair struct {
+	Key   Object
+	Value Object
+}
+
+// NewMap returns a new empty map
+func NewMap() *Map {
+	return &Map{Elements: make(map[HashKey]MapPair)}
+}
+
+// Set adds a key to the map or replaces its value
+func (m *Map) Set(key Hashable, val Object) {
+	hash := key.HashKey()
+	if _, ok := m.Elements[hash]; !ok {
+		m.Order = append(m.Order, hash)
+	}
+	// the map holds the object itself, not the hash it was given with
+	var obj Object = key
+	if k, ok := key.(*Key); ok {
+		obj = k.Object
+	}
+	m.Elements[hash] = MapPair{Key: obj, Value: val}
+}
+
+// Get returns the value stored under a key
+func (m *Map) Get(key Hashable) (Object, bool) {
+	pair, ok := m.Elements[key.HashKey()]
+	return pair.Value, ok
+}
+
+// Pairs returns the keys and values of the map in insertion order
+func (m *Map) Pairs() []MapPair {
+	pairs := make([]MapPair, 0, len(m.Order))
+	for _, hash := range m.Order {
+		pairs = append(pairs, m.Elements[hash])
+	}
+	return pairs
 }
 
 // Type implements Object for Map
@@ -221,10 +322,13 @@ func (m *Map) Type() string { return MAP }
 func (m *Map) Inspect() string {
 	var out bytes.Buffer
 
-	out.WriteString("{\n")
-	for idx, val := range m.Elements {
-		out.WriteString(fmt.Sprintf("%T : %s,\n", idx, val.Inspect()))
+	pairs := []string{}
+	for _, pair := range m.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
 	out.WriteString("}")
 
 	return out.String()
@@ -235,11 +339,127 @@ func (m *Map) Display() {
 	fmt.Println(m.Inspect())
 }
 
+// Set represents an unordered collection of unique hashable values
+type Set struct {
+	Elements map[HashKey]Object
+	// Order holds the elements' keys in the order they were first added
+	Order []HashKey
+}
+
+// NewSet returns a new empty set
+func NewSet() *Set {
+	return &Set{Elements: make(map[HashKey]Object)}
+}
+
+// Add adds an element to the set if it is not already in it
+func (s *Set) Add(elem Hashable) {
+	hash := elem.HashKey()
+	if _, ok := s.Elements[hash]; ok {
+		return
+	}
+	s.Order = append(s.Order, hash)
+	// as with Map, the set holds the object itself
+	var obj Object = elem
+	if k, ok := elem.(*Key); ok {
+		obj = k.Object
+	}
+	s.Elements[hash] = obj
+}
+
+// Has returns whether an element is in the set
+func (s *Set) Has(elem Hashable) bool {
+	_, ok := s.Elements[elem.HashKey()]
+	return ok
+}
+
+// Items returns the elements of the set in insertion order
+func (s *Set) Items() []Object {
+	items := make([]Object, 0, len(s.Order))
+	for _, hash := range s.Order {
+		items = append(items, s.Elements[hash])
+	}
+	return items
+}
+
+// Union returns a new set with the elements of both sets
+func (s *Set) Union(other *Set) *Set {
+	res := NewSet()
+	for _, set := range []*Set{s, other} {
+		for _, hash := range set.Order {
+			res.add(hash, set.Elements[hash])
+		}
+	}
+	return res
+}
+
+// Intersect returns a new set with the elements that are in both sets
+func (s *Set) Intersect(other *Set) *Set {
+	res := NewSet()
+	for _, hash := range s.Order {
+		if _, ok := other.Elements[hash]; ok {
+			res.add(hash, s.Elements[hash])
+		}
+	}
+	return res
+}
+
+// Difference returns a new set with the elements that are not in other
+func (s *Set) Difference(other *Set) *Set {
+	res := NewSet()
+	for _, hash := range s.Order {
+		if _, ok := other.Elements[hash]; !ok {
+			res.add(hash, s.Elements[hash])
+		}
+	}
+	return res
+}
+
+func (s *Set) add(hash HashKey, obj Object) {
+	if _, ok := s.Elements[hash]; !ok {
+		s.Order = append(s.Order, hash)
+		s.Elements[hash] = obj
+	}
+}
+
+// Type implements Object for Set
+func (s *Set) Type() string { return SET }
+
+// Inspect implements Object for Set.
+// An empty set is shown as set(), since {} is an empty map.
+func (s *Set) Inspect() string {
+	if len(s.Order) == 0 {
+		return "set()"
+	}
+	elems := []string{}
+	for _, elem := range s.Items() {
+		elems = append(elems, elem.Inspect())
+	}
+	return "{" + strings.Join(elems, ", ") + "}"
+}
+
+// Display implements Object for Set
+func (s *Set) Display() {
+	fmt.Println(s.Inspect())
+}
+
 // Hashable defines whether a type can be used as a key in a Map
 type Hashable interface {
+	Object
 	HashKey() HashKey
 }
 
+// Key is an object along with a hash that was computed for it,
+// so that objects that cannot hash themselves can be used in a Map
+type Key struct {
+	Object
+	Hash HashKey
+}
+
+// HashKey implements Hashable for Key
+func (k *Key) HashKey() HashKey {
+	return k.Hash
+}
+
 // HashKey defines the key used in the Map
 type HashKey struct {
 	Type  string
@@ -270,6 +490,25 @@ func (i *Integer) HashKey() HashKey {
 	}
 }
 
+// CombineHashKeys hashes a sequence of keys into the key of a tuple.
+// Tuple does not implement Hashable, as it can only be hashed if every
+// one of its elements can be, so the evaluator hashes their elements and
+// combines them, wrapping the tuple in a Key.
+func CombineHashKeys(keys []HashKey) HashKey {
+	hasher := fnv.New64a()
+	buf := make([]byte, 8)
+	for _, key := range keys {
+		hasher.Write([]byte(key.Type))
+		binary.LittleEndian.PutUint64(buf, key.Value)
+		hasher.Write(buf)
+	}
+
+	return HashKey{
+		Type:  TUPLE,
+		Value: hasher.Sum64(),
+	}
+}
+
 //! Hashable cannot be implemented for floats or composite types like map or arrays
 // func (f *Float) HashKey() HashKey {
 // 	hasher := fnv.New64a
@@ -289,6 +528,38 @@ func (s *String) HashKey() HashKey {
 	}
 }
 
+// Range represents a sequence of integers from Start up to but not including
+// End, counting by Step
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+// Type implements Object for Range
+func (r *Range) Type() string { return RANGE }
+
+// Inspect implements Object for Range
+func (r *Range) Inspect() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// Display implements Object for Range
+func (r *Range) Display() {
+	fmt.Println(r.Inspect())
+}
+
+// Len returns the number of integers in the range
+func (r *Range) Len() int64 {
+	if r.Step > 0 && r.Start < r.End {
+		return (r.End - r.Start + r.Step - 1) / r.Step
+	}
+	if r.Step < 0 && r.Start > r.End {
+		return (r.Start - r.End - r.Step - 1) / -r.Step
+	}
+	return 0
+}
+
 // Index represents an array or map index
 type Index struct {
 	Ident Object
@@ -341,7 +612,12 @@ func (r *Return) Display() {
 }
 
 //Break represents a break statement
-type Break struct{}
+type Break struct {
+	// Label is the loop being broken out of, or empty for the innermost loop
+	Label string
+	// Value is what the loop evaluates to, or nil if there is none
+	Value Object
+}
 
 //Type implements Object for Break
 func (b *Break) Type() string { return BREAK }
@@ -356,9 +632,48 @@ func (b *Break) Display() {
 	fmt.Println(b.Inspect())
 }
 
+//Continue represents a continue statement
+type Continue struct {
+	// Label is the loop being continued, or empty for the innermost loop
+	Label string
+}
+
+//Type implements Object for Continue
+func (c *Continue) Type() string { return CONTINUE }
+
+//Inspect implements Object for Continue
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+//Display implements Object for Continue
+func (c *Continue) Display() {
+	fmt.Println(c.Inspect())
+}
+
+// Iterator steps through the items of a collection
+type Iterator struct {
+	// Next returns the index or key and the element of the next item,
+	// and false once there are no items left
+	Next func() (Object, Object, bool)
+}
+
+// Type implements Object for Iterator
+func (it *Iterator) Type() string { return ITERATOR }
+
+// Inspect implements Object for Iterator
+func (it *Iterator) Inspect() string {
+	return "iterator"
+}
+
+// Display implements Object for Iterator
+func (it *Iterator) Display() {
+	fmt.Println(it.Inspect())
+}
+
 // Function represents a function in the environment
 type Function struct {
-	Params []*ast.Identifier
+	Params []ast.Pattern
 	Body   *ast.BlockStatement
 	Env    *Environment
 }
@@ -409,6 +724,132 @@ func (b *Builtin) Inspect() string {
 // Display implements Object for Builtin
 func (b *Builtin) Display() {}
 
+// Module is an imported file, whose top level bindings are its members
+type Module struct {
+	Name string
+	// Path is the absolute path of the file the module was loaded from
+	Path string
+	Env  *Environment
+}
+
+// Type implements Object for Module
+func (m *Module) Type() string { return MODULE }
+
+// Inspect implements Object for Module
+func (m *Module) Inspect() string {
+	return fmt.Sprintf("module %s (%s)", m.Name, m.Path)
+}
+
+// Display implements Object for Module
+func (m *Module) Display() {
+	fmt.Printf("%s\n", m.Inspect())
+}
+
+// Class is a declared class, which is called to create instances of it
+type Class struct {
+	Name   string
+	Fields []*ast.LetStatement
+	// Methods take the instance they are called on as their first parameter
+	Methods map[string]*Function
+	// Env is where the class was declared, and where field defaults are evaluated
+	Env *Environment
+	// Traits are the traits the class implements, by name
+	Traits map[string]*Trait
+}
+
+// Implements reports whether the class implements the named trait
+func (c *Class) Implements(trait string) bool {
+	_, ok := c.Traits[trait]
+	return ok
+}
+
+// Type implements Object for Class
+func (c *Class) Type() string { return CLASS }
+
+// Inspect implements Object for Class
+func (c *Class) Inspect() string {
+	return fmt.Sprintf("class %s", c.Name)
+}
+
+// Display implements Object for Class
+func (c *Class) Display() {
+	fmt.Printf("%s\n", c.Inspect())
+}
+
+// Trait is a set of methods that a class can implement
+type Trait struct {
+	Name string
+	// Methods maps the name of each method to its number of
+	// parameters, including the receiver
+	Methods map[string]int
+}
+
+// Type implements Object for Trait
+func (t *Trait) Type() string { return TRAIT }
+
+// Inspect implements Object for Trait
+func (t *Trait) Inspect() string {
+	return fmt.Sprintf("trait %s", t.Name)
+}
+
+// Display implements Object for Trait
+func (t *Trait) Display() {
+	fmt.Printf("%s\n", t.Inspect())
+}
+
+// Instance is an object created from a class
+type Instance struct {
+	Class  *Class
+	Fields map[string]Object
+}
+
+// Type implements Object for Instance
+func (i *Instance) Type() string { return INSTANCE }
+
+// Inspect implements Object for Instance
+func (i *Instance) Inspect() string {
+	var out bytes.Buffer
+
+	fields := []string{}
+	for _, field := range i.Class.Fields {
+		name := field.Name.Value
+		fields = append(fields, name+": "+i.Fields[name].Inspect())
+	}
+
+	out.WriteString(i.Class.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Display implements Object for Instance
+func (i *Instance) Display() {
+	fmt.Printf("%s\n", i.Inspect())
+}
+
+// BoundMethod is a method along with the instance it was accessed on,
+// which is passed as the receiver when it is called
+type BoundMethod struct {
+	Name     string
+	Receiver *Instance
+	Method   *Function
+}
+
+// Type implements Object for BoundMethod
+func (bm *BoundMethod) Type() string { return METHOD }
+
+// Inspect implements Object for BoundMethod
+func (bm *BoundMethod) Inspect() string {
+	return fmt.Sprintf("method %s.%s", bm.Receiver.Class.Name, bm.Name)
+}
+
+// Display implements Object for BoundMethod
+func (bm *BoundMethod) Display() {
+	fmt.Printf("%s\n", bm.Inspect())
+}
+
 // StmtResults is the results returned by a program
 type StmtResults struct {
 	Results []Object
@@ -484,3 +925,13 @@ func IsBreak(o Object) bool {
 		return false
 	}
 }
+
+// IsContinue checks whether a result is Continue
+func IsContinue(o Object) bool {
+	switch o.(type) {
+	case *Continue:
+		return true
+	default:
+		return false
+	}
+}
@@ -9,22 +9,13 @@ import (
 
 func (p *Parser) parseFuncDecl() ast.Declaration {
 	fndecl := &ast.FunctionDecl{Token: p.current}
-	p.advance()
-	if !p.curTokenIs(lexer.IDENT) {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected identifier, got %s", p.current.Literal),
-			Con: p.current.Pos,
-		})
+	if !p.expectNext(lexer.IDENT, "identifier") {
 		return nil
 	}
 	fndecl.Name = p.parseIdentifier().(*ast.Identifier)
 
-	p.advance()
-	if !p.curTokenIs(lexer.LPAREN) {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected `(`, got %s", p.current.Literal),
-			Con: p.current.Pos,
-		})
+	if !p.expectNext(lexer.LPAREN, "`(`") {
+		return nil
 	}
 	fndecl.Params = p.parseFunctionParams()
 	if fndecl.Params == nil {
@@ -32,20 +23,213 @@ func (p *Parser) parseFuncDecl() ast.Declaration {
 	}
 	p.advance()
 	// p.next should now be lbrace
-	if p.nextTokenIs(lexer.LBRACE) {
-		p.advance()
-		body := p.parseBlockStatement()
-		if body == nil {
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+	body := p.parseFunctionBody()
+	if body == nil {
+		return nil
+	}
+	fndecl.Body = body
+
+	return fndecl
+}
+
+func (p *Parser) parseClassDecl() ast.Declaration {
+	class := &ast.ClassDecl{Token: p.current}
+	if !p.expectNext(lexer.IDENT, "class name") {
+		return nil
+	}
+	class.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+	close, ok := p.parseDeclBody("Class "+class.Name.Value, func() *ast.Identifier {
+		switch {
+		case p.curTokenIs(lexer.LET):
+			field := p.parseLetStatement()
+			if field == nil {
+				return nil
+			}
+			if field.Pattern != nil {
+				p.errors = append(p.errors, Err{
+					Msg: "Class fields cannot be destructured",
+					Con: field.Pattern.Context(),
+				})
+				return nil
+			}
+			class.Fields = append(class.Fields, field)
+			return field.Name
+
+		case p.curTokenIs(lexer.FUNCTION) && p.nextTokenIs(lexer.IDENT):
+			method := p.parseMethod()
+			if method == nil {
+				return nil
+			}
+			class.Methods = append(class.Methods, method)
+			return method.Name
+
+		default:
+			p.unexpected(p.current, "field or method")
+			return nil
+		}
+	})
+	if !ok {
+		return nil
+	}
+	class.Close = close
+
+	return class
+}
+
+func (p *Parser) parseTraitDecl() ast.Declaration {
+	trait := &ast.TraitDecl{Token: p.current}
+	if !p.expectNext(lexer.IDENT, "trait name") {
+		return nil
+	}
+	trait.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+	close, ok := p.parseDeclBody("Trait "+trait.Name.Value, func() *ast.Identifier {
+		if !p.curTokenIs(lexer.FUNCTION) {
+			p.unexpected(p.current, "method signature")
 			return nil
 		}
-		fndecl.Body = body
-	} else {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected block, got %s", p.next.Literal),
-			Con: p.next.Pos,
+		sig := p.parseFunctionSig()
+		if sig == nil {
+			return nil
+		}
+		trait.Methods = append(trait.Methods, sig)
+		return sig.Name
+	})
+	if !ok {
+		return nil
+	}
+	trait.Close = close
+
+	return trait
+}
+
+func (p *Parser) parseImplDecl() ast.Declaration {
+	impl := &ast.ImplDecl{Token: p.current}
+	if !p.expectNext(lexer.IDENT, "trait name") {
+		return nil
+	}
+	impl.Trait = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectNext(lexer.FOR, "`for`") {
+		return nil
+	}
+	if !p.expectNext(lexer.IDENT, "class name") {
+		return nil
+	}
+	impl.Class = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+	what := fmt.Sprintf("Impl of %s for %s", impl.Trait.Value, impl.Class.Value)
+	close, ok := p.parseDeclBody(what, func() *ast.Identifier {
+		if !p.curTokenIs(lexer.FUNCTION) || !p.nextTokenIs(lexer.IDENT) {
+			p.unexpected(p.current, "method")
+			return nil
+		}
+		method := p.parseMethod()
+		if method == nil {
+			return nil
+		}
+		impl.Methods = append(impl.Methods, method)
+		return method.Name
+	})
+	if !ok {
+		return nil
+	}
+	impl.Close = close
+
+	return impl
+}
+
+// parseDeclBody parses the members of a class, trait or impl, starting at
+// its opening brace. parseMember parses the member starting at p.current
+// and returns its name, or nil if it failed to parse. what names the
+// declaration in errors about members declared twice.
+// It returns the closing brace, or false if the body was not closed.
+func (p *Parser) parseDeclBody(what string, parseMember func() *ast.Identifier) (lexer.Token, bool) {
+	p.advance()
+
+	p.blocks++
+	defer func() { p.blocks-- }()
+
+	members := map[string]bool{}
+	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start, errs := p.current, len(p.errors)
+		name := parseMember()
+		if name == nil || p.failed(name, errs) {
+			p.synchronize(start)
+			continue
+		}
+		if members[name.Value] {
+			p.errors = append(p.errors, Err{
+				Msg: fmt.Sprintf("%s already has a member %s", what, name.Value),
+				Con: name.Context(),
+			})
+		}
+		members[name.Value] = true
+		p.advance()
+	}
+	if p.curTokenIs(lexer.EOF) {
+		p.unexpected(p.current, "`}`")
+		return lexer.Token{}, false
+	}
+	return p.current, true
+}
+
+// parseMethod parses a function declaration that takes a receiver
+func (p *Parser) parseMethod() *ast.FunctionDecl {
+	decl := p.parseFuncDecl()
+	if decl == nil {
+		return nil
+	}
+	method := decl.(*ast.FunctionDecl)
+	if len(method.Params) == 0 {
+		p.errors = append(p.errors, Err{
+			Msg: fmt.Sprintf("Method %s must take a receiver", method.Name.Value),
+			Con: method.Name.Context(),
 		})
 		return nil
 	}
+	return method
+}
 
-	return fndecl
+func (p *Parser) parseFunctionSig() *ast.FunctionSig {
+	sig := &ast.FunctionSig{Token: p.current}
+	if !p.expectNext(lexer.IDENT, "identifier") {
+		return nil
+	}
+	sig.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+
+	if !p.expectNext(lexer.LPAREN, "`(`") {
+		return nil
+	}
+	sig.Params = p.parseFunctionParams()
+	if sig.Params == nil {
+		return nil
+	}
+	p.advance()
+	sig.Close = p.current
+	if len(sig.Params) == 0 {
+		p.errors = append(p.errors, Err{
+			Msg: fmt.Sprintf("Method %s must take a receiver", sig.Name.Value),
+			Con: sig.Name.Context(),
+		})
+		return nil
+	}
+
+	if p.nextTokenIs(lexer.SEMICOL) {
+		p.advance()
+	}
+	return sig
 }
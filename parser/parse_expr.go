@@ -46,6 +46,39 @@ func (p *Parser) parseStrLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseInterpStr() ast.Expression {
+	lit := &ast.InterpStr{Token: p.current}
+
+	// p.current is a STRHEAD or STRMID token
+	for {
+		if p.current.Literal != "" {
+			lit.Parts = append(lit.Parts, &ast.Str{Token: p.current, Inner: p.current.Literal})
+		}
+
+		p.advance()
+		expr := p.parseExpression(LOWEST)
+		if expr == nil {
+			return nil
+		}
+		lit.Parts = append(lit.Parts, expr)
+
+		p.advance()
+		switch p.current.Type {
+		case lexer.STRMID:
+			continue
+		case lexer.STRTAIL:
+			if p.current.Literal != "" {
+				lit.Parts = append(lit.Parts, &ast.Str{Token: p.current, Inner: p.current.Literal})
+			}
+			lit.Close = p.current
+			return lit
+		default:
+			p.unexpected(p.current, "`}` to close interpolation")
+			return nil
+		}
+	}
+}
+
 func (p *Parser) parseBoolLiteral() ast.Expression {
 	lit := &ast.Bool{Token: p.current}
 	value, err := strconv.ParseBool(p.current.Literal)
@@ -63,90 +96,190 @@ func (p *Parser) parseBoolLiteral() ast.Expression {
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	lit := &ast.Array{Token: p.current}
 
-	lit.Elements = p.parseExpressionList(lexer.RSBRKT)
+	if p.nextIsRange() {
+		return p.parseRangeLiteral(lit.Token, nil)
+	}
+	if p.nextTokenIs(lexer.RSBRKT) {
+		p.advance()
+		lit.Elements = []ast.Expression{}
+		lit.Close = p.current
+		return lit
+	}
+
+	p.advance()
+	first := p.parseExpression(LOWEST)
+	if first == nil {
+		return nil
+	}
+	if p.nextIsRange() {
+		return p.parseRangeLiteral(lit.Token, first)
+	}
+
+	lit.Elements = p.parseRestOfList(first, lexer.RSBRKT)
+	if lit.Elements == nil {
+		return nil
+	}
+	lit.Close = p.current
 
 	return lit
 }
 
+func (p *Parser) nextIsRange() bool {
+	return p.nextTokenIs(lexer.RANGE) || p.nextTokenIs(lexer.INCLRANGE)
+}
+
+// parseRangeLiteral parses a range literal such as [1..10] from the range
+// operator in p.next, given the opening bracket and the start of the range
+func (p *Parser) parseRangeLiteral(open lexer.Token, start ast.Expression) ast.Expression {
+	rng := p.parseRange(start)
+	if rng == nil {
+		return nil
+	}
+	if !p.expectNext(lexer.RSBRKT, "`]`") {
+		return nil
+	}
+	rng.Token = open
+	rng.Close = p.current
+
+	if rng.End == nil {
+		p.errors = append(p.errors, Err{
+			Msg: "Range literal must have an end",
+			Con: rng.Context(),
+		})
+		return nil
+	}
+	return rng
+}
+
+// parseRange parses the range operator in p.next and the end and step following it
+func (p *Parser) parseRange(start ast.Expression) *ast.RangeExpr {
+	p.advance()
+	rng := &ast.RangeExpr{
+		Token:     p.current,
+		Start:     start,
+		Inclusive: p.curTokenIs(lexer.INCLRANGE),
+	}
+
+	if !p.nextTokenIs(lexer.RSBRKT) && !p.nextTokenIs(lexer.COLON) {
+		p.advance()
+		rng.End = p.parseExpression(LOWEST)
+		if rng.End == nil {
+			return nil
+		}
+	}
+	if rng.Inclusive && rng.End == nil {
+		p.unexpected(p.next, "end of inclusive range")
+		return nil
+	}
+
+	if p.nextTokenIs(lexer.COLON) {
+		p.advance()
+		p.advance()
+		rng.Step = p.parseExpression(LOWEST)
+		if rng.Step == nil {
+			return nil
+		}
+	}
+
+	return rng
+}
+
+// parseMapLiteral parses a map literal, or a set literal if there is
+// no colon after the first element. {} is always an empty map.
 func (p *Parser) parseMapLiteral() ast.Expression {
 	lit := &ast.Map{Token: p.current}
 	lit.Elements = make(map[ast.Expression]ast.Expression)
 
 	if p.nextTokenIs(lexer.RBRACE) {
 		p.advance()
+		lit.Close = p.current
 		return lit
 	}
 
-	if !p.curTokenIs(lexer.LBRACE) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected {, got %s", p.current.Literal),
-			Con: p.current.Pos,
-		})
-		return nil
-	}
 	for !p.nextTokenIs(lexer.RBRACE) {
 		p.advance()
 		idx := p.parseExpression(LOWEST)
-		if !p.nextTokenIs(lexer.COLON) {
-			p.errors = append(p.errors, Err{
-				Msg: fmt.Sprintf("Expected :, got %s", p.next.Literal),
-				Con: p.current.Pos,
-			})
+		if idx == nil {
+			return nil
+		}
+		if len(lit.Keys) == 0 && !p.nextTokenIs(lexer.COLON) {
+			return p.parseSetLiteral(lit.Token, idx)
+		}
+		if !p.expectNext(lexer.COLON, "`:`") {
 			return nil
 		}
-		p.advance() //current is now lexer.COLON
 		p.advance() //current is start of next expression
 		val := p.parseExpression(LOWEST)
+		if val == nil {
+			return nil
+		}
 		lit.Elements[idx] = val
+		lit.Keys = append(lit.Keys, idx)
 
-		if !p.nextTokenIs(lexer.RBRACE) {
-			p.advance()
-			if p.curTokenIs(lexer.COMMA) {
-				continue
-			}
-			p.errors = append(p.errors, Err{
-				Msg: fmt.Sprintf("Expected comma, got %s", p.next.Literal),
-				Con: p.current.Pos,
-			})
+		if !p.nextTokenIs(lexer.RBRACE) && !p.expectNext(lexer.COMMA, "`,` or `}`") {
 			return nil
 		}
 	}
 
-	if !p.nextTokenIs(lexer.RBRACE) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected }, got %s", p.next.Literal),
-			Con: p.current.Pos,
-		})
-		return nil
-	}
-
 	p.advance()
+	lit.Close = p.current
 
 	return lit
 }
 
+// parseSetLiteral parses the rest of a set literal after its first element
+func (p *Parser) parseSetLiteral(open lexer.Token, first ast.Expression) ast.Expression {
+	set := &ast.Set{Token: open}
+	set.Elements = p.parseRestOfList(first, lexer.RBRACE)
+	if set.Elements == nil {
+		return nil
+	}
+	set.Close = p.current
+	return set
+}
+
 func (p *Parser) parseIndexExpr(left ast.Expression) ast.Expression {
 	lit := &ast.IndexExpr{Token: p.current}
 
-	p.advance()
-
 	lit.Left = left
 
-	lit.Index = p.parseExpression(LOWEST)
+	// a slice such as xs[..n] has no start
+	if p.nextIsRange() {
+		if rng := p.parseRange(nil); rng != nil {
+			lit.Index = rng
+		}
+	} else {
+		p.advance()
+		lit.Index = p.parseExpression(LOWEST)
+		if lit.Index != nil && p.nextIsRange() {
+			if rng := p.parseRange(lit.Index); rng != nil {
+				lit.Index = rng
+			} else {
+				lit.Index = nil
+			}
+		}
+	}
 
-	if !p.nextTokenIs(lexer.RSBRKT) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected ], got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if lit.Index == nil {
+		return nil
 	}
 
-	p.advance()
+	if !p.expectNext(lexer.RSBRKT, "`]`") {
+		return nil
+	}
+	lit.Close = p.current
 
 	return lit
 }
 
+// parseGroupedExpr parses an expression in parens, or a tuple literal
+// if the parens are empty or there is a comma after the first expression
 func (p *Parser) parseGroupedExpr() ast.Expression {
+	open := p.current
+	if p.nextTokenIs(lexer.RPAREN) {
+		p.advance()
+		return &ast.Tuple{Token: open, Elements: []ast.Expression{}, Close: p.current}
+	}
 	p.advance()
 
 	expr := p.parseExpression(LOWEST)
@@ -155,15 +288,19 @@ func (p *Parser) parseGroupedExpr() ast.Expression {
 		return nil
 	}
 
-	if !p.nextTokenIs(lexer.RPAREN) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected `)`, got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
-		return nil
+	if p.nextTokenIs(lexer.COMMA) {
+		tuple := &ast.Tuple{Token: open}
+		tuple.Elements = p.parseRestOfList(expr, lexer.RPAREN)
+		if tuple.Elements == nil {
+			return nil
+		}
+		tuple.Close = p.current
+		return tuple
 	}
 
-	p.advance()
+	if !p.expectNext(lexer.RPAREN, "`)`") {
+		return nil
+	}
 
 	return expr
 }
@@ -200,29 +337,70 @@ func (p *Parser) parseInfixExpr(left ast.Expression) ast.Expression {
 	return expr
 }
 
-func (p *Parser) parseIfExpression() ast.Expression {
-	expr := &ast.IfExpression{Token: p.current}
+// compoundOps maps each assignment token to the operator it applies
+var compoundOps = map[string]string{
+	lexer.ASSIGN:    "",
+	lexer.ADDASSIGN: lexer.ADD,
+	lexer.SUBASSIGN: lexer.SUB,
+	lexer.MULASSIGN: lexer.MUL,
+	lexer.DIVASSIGN: lexer.DIV,
+	lexer.BWOASSIGN: lexer.BWOR,
+	lexer.BWAASSIGN: lexer.BWAND,
+	lexer.BWNASSIGN: lexer.BWNOT,
+}
+
+func (p *Parser) parseAssignExpr(target ast.Expression) ast.Expression {
+	expr := &ast.AssignExpr{
+		Token:    p.current,
+		Target:   target,
+		Operator: compoundOps[p.current.Type],
+	}
 
-	if !p.nextTokenIs(lexer.LPAREN) {
+	switch target := target.(type) {
+	case *ast.Identifier, *ast.IndexExpr:
+	case *ast.DotExpression:
+		// only fields can be assigned to, not method calls
+		if _, ok := target.Right.(*ast.Identifier); ok {
+			break
+		}
 		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected `(` after 'if', got %s", p.next.Literal),
-			Con: p.next.Pos,
+			Msg: fmt.Sprintf("Cannot assign to %s", target.String()),
+			Con: target.Context(),
+		})
+		return nil
+	default:
+		p.errors = append(p.errors, Err{
+			Msg: fmt.Sprintf("Cannot assign to %s", target.String()),
+			Con: target.Context(),
 		})
 		return nil
 	}
+
 	p.advance()
+	// assignment is right associative, so a = b = c assigns c to both
+	expr.Value = p.parseExpression(ASSIGN - 1)
+	if expr.Value == nil {
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expr := &ast.IfExpression{Token: p.current}
+
+	if !p.expectNext(lexer.LPAREN, "`(` after 'if'") {
+		return nil
+	}
 	// p.current is now LPAREN
 
 	expr.Condition = p.parseExpression(LOWEST)
+	if expr.Condition == nil {
+		return nil
+	}
 
-	if !p.nextTokenIs(lexer.LBRACE) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected start of block, got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if !p.expectNext(lexer.LBRACE, "start of block") {
 		return nil
 	}
-	p.advance()
 
 	res := p.parseBlockStatement()
 	if res == nil {
@@ -247,10 +425,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 			p.advance()
 			expr.Alternative = p.parseIfExpression()
 		} else {
-			p.errors = append(p.errors, Err{
-				Msg: fmt.Sprintf("Expected 'if' or `{`, got %s", p.next.Literal),
-				Con: p.next.Pos,
-			})
+			p.unexpected(p.next, "'if' or `{`")
 			return nil
 		}
 
@@ -266,15 +441,9 @@ func (p *Parser) parseFnLiteral() ast.Expression {
 		panic(fmt.Sprintf("Wrong token, got %s", p.current.Type))
 	}
 
-	if !p.nextTokenIs(lexer.LPAREN) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected `(`, got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if !p.expectNext(lexer.LPAREN, "`(`") {
 		return nil
 	}
-
-	p.advance()
 	// p.current is now lparen
 
 	lit.Params = p.parseFunctionParams()
@@ -285,78 +454,178 @@ func (p *Parser) parseFnLiteral() ast.Expression {
 
 	p.advance()
 	// p.next should now be lbrace
-	if p.nextTokenIs(lexer.LBRACE) {
-		p.advance()
-		body := p.parseBlockStatement()
-		if body == nil {
-			return nil
-		}
-		lit.Body = body
-	} else {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected block, got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if !p.expectNext(lexer.LBRACE, "`{`") {
 		return nil
 	}
+	body := p.parseFunctionBody()
+	if body == nil {
+		return nil
+	}
+	lit.Body = body
 
 	return lit
 }
 
-func (p *Parser) parseFunctionParams() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+// parseFunctionBody parses the block of a function,
+// where the labels of enclosing loops cannot be used
+func (p *Parser) parseFunctionBody() *ast.BlockStatement {
+	labels := p.labels
+	p.labels = nil
+	defer func() { p.labels = labels }()
+
+	return p.parseBlockStatement()
+}
+
+// parseFunctionParams parses the parameters of a function, each of which
+// is a name or a pattern. Parameters may have defaults, which every
+// parameter after the first with a default must also have, and the last
+// parameter may be a rest parameter that collects any remaining arguments.
+func (p *Parser) parseFunctionParams() []ast.Pattern {
+	params := []ast.Pattern{}
+	defaults := false
 
 	//p.current is lparen
 	if p.nextTokenIs(lexer.RPAREN) {
-		return identifiers
+		return params
 	}
 
-	p.advance()
-	//p.current should now be IDENT
-
-	ident := &ast.Identifier{Token: p.current, Value: p.current.Literal}
-	identifiers = append(identifiers, ident)
+	for {
+		var param ast.Pattern
+		switch {
+		case p.nextTokenIs(lexer.ELLIPSIS):
+			p.advance()
+			if param = p.parseRestParam(); param == nil {
+				return nil
+			}
+		case p.nextTokenIs(lexer.IDENT) || startsPattern(p.next.Type):
+			p.advance()
+			if param = p.parsePattern(); param == nil {
+				return nil
+			}
+			if p.nextTokenIs(lexer.ASSIGN) {
+				p.advance()
+				p.advance()
+				def := p.parseExpression(LOWEST)
+				if def == nil {
+					return nil
+				}
+				param = &ast.DefaultParam{Param: param, Default: def}
+				defaults = true
+			} else if defaults {
+				p.errors = append(p.errors, Err{
+					Msg: fmt.Sprintf("Parameter %s must have a default, as it follows one that does", param.String()),
+					Con: param.Context(),
+				})
+				return nil
+			}
+		default:
+			p.unexpected(p.next, "parameter name")
+			return nil
+		}
+		params = append(params, param)
 
-	// p.next should now be comma
-	for p.nextTokenIs(lexer.COMMA) {
-		p.advance() //p.current == comma
-		p.advance() //p.current == ident
-		ident = &ast.Identifier{Token: p.current, Value: p.current.Literal}
-		identifiers = append(identifiers, ident)
+		// p.next should now be comma or rparen
+		if !p.nextTokenIs(lexer.COMMA) {
+			break
+		}
+		p.advance()
 	}
 
 	if !p.nextTokenIs(lexer.RPAREN) {
+		p.unexpected(p.next, "`,` or `)`")
+		return nil
+	}
+
+	return params
+}
+
+// parseRestParam parses a rest parameter, which must be the last parameter
+func (p *Parser) parseRestParam() *ast.RestPattern {
+	param := p.parseRestPattern()
+	if param == nil {
+		return nil
+	}
+	switch {
+	case p.nextTokenIs(lexer.ASSIGN):
 		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected `)`, got %s", p.next.Literal),
+			Msg: "Rest parameters cannot have defaults",
 			Con: p.next.Pos,
 		})
 		return nil
+	case p.nextTokenIs(lexer.COMMA):
+		p.errors = append(p.errors, Err{
+			Msg: "The rest parameter must be the last parameter",
+			Con: param.Context(),
+		})
+		return nil
 	}
-
-	return identifiers
+	return param
 }
 
 func (p *Parser) parseFunctionCall(fn ast.Expression) ast.Expression {
-	exp := &ast.FunctionCall{Token: p.current, Ident: fn}
-	exp.Params = p.parseExpressionList(lexer.RPAREN)
+	exp := &ast.FunctionCall{Token: p.current, Ident: fn, Params: []ast.Expression{}}
+	keywords := map[string]bool{}
 
-	if exp.Params == nil {
-		return nil
-	}
+	for !p.nextTokenIs(lexer.RPAREN) {
+		p.advance()
+		switch {
+		case p.curTokenIs(lexer.IDENT) && p.nextTokenIs(lexer.COLON):
+			kw := &ast.KeywordArg{Name: &ast.Identifier{Token: p.current, Value: p.current.Literal}}
+			if keywords[kw.Name.Value] {
+				p.errors = append(p.errors, Err{
+					Msg: fmt.Sprintf("Keyword argument %s is given more than once", kw.Name.Value),
+					Con: kw.Name.Context(),
+				})
+				return nil
+			}
+			keywords[kw.Name.Value] = true
+			p.advance()
+			p.advance()
+			if kw.Value = p.parseExpression(LOWEST); kw.Value == nil {
+				return nil
+			}
+			exp.Keywords = append(exp.Keywords, kw)
 
-	return exp
-}
+		case len(exp.Keywords) > 0:
+			p.errors = append(p.errors, Err{
+				Msg: "Positional arguments cannot follow keyword arguments",
+				Con: p.current.Pos,
+			})
+			return nil
 
-func (p *Parser) parseExpressionList(delim string) []ast.Expression {
-	elems := []ast.Expression{}
+		case p.curTokenIs(lexer.ELLIPSIS):
+			spread := &ast.Spread{Token: p.current}
+			p.advance()
+			if spread.Value = p.parseExpression(LOWEST); spread.Value == nil {
+				return nil
+			}
+			exp.Params = append(exp.Params, spread)
 
-	if p.nextTokenIs(delim) {
+		default:
+			arg := p.parseExpression(LOWEST)
+			if arg == nil {
+				return nil
+			}
+			exp.Params = append(exp.Params, arg)
+		}
+
+		if !p.nextTokenIs(lexer.COMMA) {
+			break
+		}
 		p.advance()
-		return elems
 	}
 
-	p.advance()
-	elems = append(elems, p.parseExpression(LOWEST))
+	if !p.expectNext(lexer.RPAREN, "`,` or `)`") {
+		return nil
+	}
+	exp.Close = p.current
+
+	return exp
+}
+
+// parseRestOfList parses the elements of a list following its first element
+func (p *Parser) parseRestOfList(first ast.Expression, delim string) []ast.Expression {
+	elems := []ast.Expression{first}
 
 	for p.nextTokenIs(lexer.COMMA) {
 		p.advance()
@@ -364,44 +633,38 @@ func (p *Parser) parseExpressionList(delim string) []ast.Expression {
 			break
 		}
 		p.advance()
-		elems = append(elems, p.parseExpression(LOWEST))
+		elem := p.parseExpression(LOWEST)
+		if elem == nil {
+			return nil
+		}
+		elems = append(elems, elem)
 	}
 
-	if !p.nextTokenIs(delim) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("ParseCallArgs: Expected `%s`, got %s", delim, p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if !p.expectNext(delim, fmt.Sprintf("`,` or `%s`", delim)) {
 		return nil
 	}
 
-	p.advance()
-
 	return elems
 }
 
+// parseDotExpression parses the member after a dot, which is a field
+// or a method call. Anything after the member, such as an index, applies
+// to the whole dot expression, so self.items[0] indexes the field.
 func (p *Parser) parseDotExpression(left ast.Expression) ast.Expression {
 	exp := &ast.DotExpression{Token: p.current, Left: left}
 
 	//p.current is DOT
-
-	p.advance()
-	//p.current is now the start of the next expr
-
-	exp.Right = p.parseExpression(DOT)
-
-	switch exp.Right.(type) {
-	case *ast.DotExpression:
-		return exp
-	case *ast.Identifier:
-		return exp
-	case *ast.FunctionCall:
-		return exp
-	default:
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected function call or field, got %T", exp.Right),
-			Con: p.current.Pos,
-		})
+	if !p.expectNext(lexer.IDENT, "function call or field") {
 		return nil
 	}
+	exp.Right = p.parseIdentifier()
+
+	if p.nextTokenIs(lexer.LPAREN) {
+		p.advance()
+		if exp.Right = p.parseFunctionCall(exp.Right); exp.Right == nil {
+			return nil
+		}
+	}
+
+	return exp
 }
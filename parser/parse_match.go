@@ -0,0 +1,118 @@
+package parser
+
+import (
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/lexer"
+)
+
+func (p *Parser) parseMatchExpr() ast.Expression {
+	expr := &ast.MatchExpr{Token: p.current}
+	p.advance()
+
+	expr.Subject = p.parseExpression(LOWEST)
+	if expr.Subject == nil {
+		return nil
+	}
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+
+	for !p.nextTokenIs(lexer.RBRACE) {
+		p.advance()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expr.Arms = append(expr.Arms, arm)
+
+		// arms that end in a brace do not need a comma after them
+		if p.nextTokenIs(lexer.COMMA) {
+			p.advance()
+		} else if !p.curTokenIs(lexer.RBRACE) {
+			break
+		}
+	}
+
+	if !p.expectNext(lexer.RBRACE, "`,` or `}`") {
+		return nil
+	}
+	expr.Close = p.current
+
+	p.checkMatch(expr)
+	return expr
+}
+
+// parseMatchArm parses an arm of a match expression, starting at its pattern
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	p.literals = true
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	p.literals = false
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if p.nextTokenIs(lexer.IF) {
+		p.advance()
+		p.advance()
+		if arm.Guard = p.parseExpression(LOWEST); arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !p.expectNext(lexer.FATARROW, "`=>`") {
+		return nil
+	}
+
+	if p.nextTokenIs(lexer.LBRACE) {
+		p.advance()
+		if arm.Body = p.parseBlockStatement(); arm.Body == nil {
+			return nil
+		}
+		return arm
+	}
+
+	p.advance()
+	start := p.current
+	body := p.parseExpression(LOWEST)
+	if body == nil {
+		return nil
+	}
+	arm.Body = &ast.BlockStatement{
+		Token:      start,
+		Statements: []ast.Statement{&ast.ExprStatement{Token: start, Expression: body}},
+	}
+	return arm
+}
+
+// checkMatch warns about arms that can never be reached, and about
+// matches that may not handle every value. A match is only known to be
+// exhaustive if it has an arm without a guard that matches anything,
+// or arms for both true and false.
+func (p *Parser) checkMatch(expr *ast.MatchExpr) {
+	exhaustive := false
+	literals := map[string]bool{}
+
+	for _, arm := range expr.Arms {
+		if exhaustive {
+			p.warn("Unreachable match arm", arm.Pattern.Context())
+			continue
+		}
+		if arm.Guard != nil {
+			continue
+		}
+		switch pattern := arm.Pattern.(type) {
+		case *ast.Identifier, *ast.WildcardPattern:
+			exhaustive = true
+		case *ast.LiteralPattern:
+			lit := pattern.String()
+			if literals[lit] {
+				p.warn("Unreachable match arm", arm.Pattern.Context())
+			}
+			literals[lit] = true
+		}
+	}
+
+	if !exhaustive && !(literals["true"] && literals["false"]) {
+		p.warn("Match is not exhaustive, add a `_` arm to handle any other value", expr.Token.Pos)
+	}
+}
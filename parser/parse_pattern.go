@@ -0,0 +1,238 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/cartoon-raccoon/lemur/ast"
+	"github.com/cartoon-raccoon/lemur/lexer"
+)
+
+// startsPattern reports whether a token can start a destructuring pattern
+func startsPattern(tt string) bool {
+	switch tt {
+	case lexer.LPAREN, lexer.LSBRKT, lexer.LBRACE:
+		return true
+	default:
+		return false
+	}
+}
+
+// parsePattern parses the pattern starting at p.current.
+// It returns nil if it failed to parse.
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.current.Type {
+	case lexer.IDENT:
+		if p.current.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.current}
+		}
+		ident := &ast.Identifier{Token: p.current, Value: p.current.Literal}
+		if !p.nextTokenIs(lexer.LBRACE) {
+			return ident
+		}
+		p.advance()
+		fields := p.parseMapPattern()
+		if fields == nil {
+			return nil
+		}
+		return &ast.ClassPattern{Class: ident, Fields: fields}
+	case lexer.INTLIT, lexer.FLTLIT, lexer.STRLIT, lexer.TRUE, lexer.FALSE, lexer.SUB:
+		if !p.literals {
+			p.unexpected(p.current, "pattern")
+			return nil
+		}
+		return p.parseLiteralPattern()
+	case lexer.LPAREN:
+		if pattern := p.parseTuplePattern(); pattern != nil {
+			return pattern
+		}
+		return nil
+	case lexer.LSBRKT:
+		if pattern := p.parseArrayPattern(); pattern != nil {
+			return pattern
+		}
+		return nil
+	case lexer.LBRACE:
+		if pattern := p.parseMapPattern(); pattern != nil {
+			return pattern
+		}
+		return nil
+	case lexer.ELLIPSIS:
+		p.errors = append(p.errors, Err{
+			Msg: "Rest patterns can only be used in arrays and tuples",
+			Con: p.current.Pos,
+		})
+		return nil
+	default:
+		p.unexpected(p.current, "pattern")
+		return nil
+	}
+}
+
+func (p *Parser) parseTuplePattern() *ast.TuplePattern {
+	pattern := &ast.TuplePattern{Token: p.current}
+	pattern.Elements = p.parsePatternList(lexer.RPAREN)
+	if pattern.Elements == nil {
+		return nil
+	}
+	pattern.Close = p.current
+	return pattern
+}
+
+func (p *Parser) parseArrayPattern() *ast.ArrayPattern {
+	pattern := &ast.ArrayPattern{Token: p.current}
+	pattern.Elements = p.parsePatternList(lexer.RSBRKT)
+	if pattern.Elements == nil {
+		return nil
+	}
+	pattern.Close = p.current
+	return pattern
+}
+
+// parsePatternList parses the patterns of a tuple or array pattern up to
+// delim, where at most one of them may be a rest pattern
+func (p *Parser) parsePatternList(delim string) []ast.Pattern {
+	elems := []ast.Pattern{}
+	rest := false
+
+	for !p.nextTokenIs(delim) {
+		p.advance()
+		var elem ast.Pattern
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			elem = p.parseRestPattern()
+			if elem != nil && rest {
+				p.errors = append(p.errors, Err{
+					Msg: "Only one rest pattern is allowed",
+					Con: elem.Context(),
+				})
+				return nil
+			}
+			rest = true
+		} else {
+			elem = p.parsePattern()
+		}
+		if elem == nil {
+			return nil
+		}
+		elems = append(elems, elem)
+
+		if !p.nextTokenIs(lexer.COMMA) {
+			break
+		}
+		p.advance()
+	}
+
+	if !p.expectNext(delim, fmt.Sprintf("`,` or `%s`", delim)) {
+		return nil
+	}
+	return elems
+}
+
+func (p *Parser) parseRestPattern() *ast.RestPattern {
+	pattern := &ast.RestPattern{Token: p.current}
+	if !p.expectNext(lexer.IDENT, "name after `...`") {
+		return nil
+	}
+	pattern.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+	return pattern
+}
+
+func (p *Parser) parseMapPattern() *ast.MapPattern {
+	pattern := &ast.MapPattern{Token: p.current}
+	keys := map[string]bool{}
+
+	for !p.nextTokenIs(lexer.RBRACE) {
+		if !p.expectNext(lexer.IDENT, "key name") {
+			return nil
+		}
+		key := &ast.Identifier{Token: p.current, Value: p.current.Literal}
+		if keys[key.Value] {
+			p.errors = append(p.errors, Err{
+				Msg: fmt.Sprintf("Key %s is destructured more than once", key.Value),
+				Con: key.Context(),
+			})
+			return nil
+		}
+		keys[key.Value] = true
+
+		var value ast.Pattern = key
+		if p.nextTokenIs(lexer.COLON) {
+			p.advance()
+			p.advance()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		}
+		pattern.Keys = append(pattern.Keys, key)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.nextTokenIs(lexer.COMMA) {
+			break
+		}
+		p.advance()
+	}
+
+	if !p.expectNext(lexer.RBRACE, "`,` or `}`") {
+		return nil
+	}
+	pattern.Close = p.current
+	return pattern
+}
+
+// parseLiteralPattern parses a literal pattern, or a range pattern
+// if the literal is followed by `..` or `..=`
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	start := p.parseLiteralValue()
+	if start == nil {
+		return nil
+	}
+	if !p.nextTokenIs(lexer.RANGE) && !p.nextTokenIs(lexer.INCLRANGE) {
+		return &ast.LiteralPattern{Value: start}
+	}
+	p.advance()
+
+	rng := &ast.RangePattern{Start: start, Inclusive: p.curTokenIs(lexer.INCLRANGE)}
+	p.advance()
+	if rng.End = p.parseLiteralValue(); rng.End == nil {
+		return nil
+	}
+	if !isIntLiteral(rng.Start) || !isIntLiteral(rng.End) {
+		p.errors = append(p.errors, Err{
+			Msg: "Range patterns must have integer bounds",
+			Con: rng.Context(),
+		})
+		return nil
+	}
+	return rng
+}
+
+// parseLiteralValue parses the literal of a literal or range pattern,
+// which is a number, string or boolean. Numbers may be negative.
+func (p *Parser) parseLiteralValue() ast.Expression {
+	switch p.current.Type {
+	case lexer.INTLIT, lexer.FLTLIT, lexer.STRLIT, lexer.TRUE, lexer.FALSE:
+		return p.prefixParseFns[p.current.Type]()
+	case lexer.SUB:
+		if !p.nextTokenIs(lexer.INTLIT) && !p.nextTokenIs(lexer.FLTLIT) {
+			p.unexpected(p.next, "number after `-`")
+			return nil
+		}
+		neg := &ast.PrefixExpr{Token: p.current, Operator: p.current.Literal}
+		p.advance()
+		if neg.Right = p.prefixParseFns[p.current.Type](); neg.Right == nil {
+			return nil
+		}
+		return neg
+	default:
+		p.unexpected(p.current, "literal")
+		return nil
+	}
+}
+
+// isIntLiteral reports whether a literal is an integer, or a negated one
+func isIntLiteral(lit ast.Expression) bool {
+	if neg, ok := lit.(*ast.PrefixExpr); ok {
+		lit = neg.Right
+	}
+	_, ok := lit.(*ast.Int)
+	return ok
+}
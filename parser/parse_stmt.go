@@ -10,28 +10,29 @@ import (
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.current}
 
-	if !p.nextTokenIs(lexer.IDENT) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected identifier, got `%s`", p.next.Type),
-			Con: p.next.Pos,
-		})
-		return nil
+	if startsPattern(p.next.Type) {
+		p.advance()
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectNext(lexer.IDENT, "identifier") {
+			return nil
+		}
+		if p.nextTokenIs(lexer.LBRACE) {
+			// a class pattern, such as let Point{x, y} = p;
+			if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+				return nil
+			}
+		} else {
+			stmt.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+		}
 	}
 
-	p.advance()
-
-	stmt.Name = &ast.Identifier{Token: p.current, Value: p.current.Literal}
-
-	if !p.nextTokenIs(lexer.ASSIGN) {
-		p.errors = append(p.errors, Err{
-			Msg: fmt.Sprintf("Expected assignment operator, got `%s`", p.next.Type),
-			Con: p.next.Pos,
-		})
+	if !p.expectNext(lexer.ASSIGN, "`=`") {
 		return nil
 	}
-
-	// fixme: a bit hacky
-	p.advance() // p.current is now assign, p.next is expr start
 	p.advance() // p.current is now expr start
 
 	val := p.parseExpression(LOWEST)
@@ -40,10 +41,9 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	}
 	stmt.Value = val
 
-	// note: this does not account for if the user forgets to put a semicolon
-	// The parser will happily continue advancing until it hits a semicolon,
-	// whenever that may be
-	p.advance() //puts the next token in p.current
+	if p.nextTokenIs(lexer.SEMICOL) {
+		p.advance()
+	}
 
 	return stmt
 }
@@ -58,7 +58,9 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	if stmt.Value == nil {
 		return nil
 	}
-	p.advance()
+	if p.nextTokenIs(lexer.SEMICOL) {
+		p.advance()
+	}
 
 	return stmt
 }
@@ -85,13 +87,18 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	p.advance()
 
+	p.blocks++
+	defer func() { p.blocks-- }()
+
 	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		start, errs := p.current, len(p.errors)
 		node := p.parseNode()
-		if node == nil {
-			return nil
+		if p.failed(node, errs) {
+			p.synchronize(start)
+			continue
 		}
 		stmt, ok := node.(ast.Statement)
-		if stmt != nil && ok {
+		if ok {
 			block.Statements = append(block.Statements, stmt)
 		} else {
 			p.errors = append(p.errors, Err{
@@ -101,6 +108,11 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.advance()
 	}
+	if p.curTokenIs(lexer.EOF) {
+		p.unexpected(p.current, "`}`")
+		return nil
+	}
+	block.Close = p.current
 
 	return block
 }
@@ -108,25 +120,18 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	while := &ast.WhileStatement{Token: p.current}
 
-	p.advance()
-
-	if !p.curTokenIs(lexer.LPAREN) {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected `(`, got %s", p.current.Literal),
-			Con: p.current.Pos,
-		})
+	if !p.expectNext(lexer.LPAREN, "`(`") {
+		return nil
 	}
 
 	while.Condition = p.parseExpression(LOWEST)
-
-	if !p.nextTokenIs(lexer.LBRACE) {
-		p.errors = append(p.errors, &Err{
-			Msg: fmt.Sprintf("Expected `{`, got %s", p.next.Literal),
-			Con: p.next.Pos,
-		})
+	if while.Condition == nil {
+		return nil
 	}
 
-	p.advance()
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
 
 	while.Body = p.parseBlockStatement()
 	if while.Body == nil {
@@ -136,8 +141,214 @@ func (p *Parser) parseWhileStatement() *ast.WhileStatement {
 	return while
 }
 
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.current}
+
+	for {
+		if !p.nextTokenIs(lexer.IDENT) && !startsPattern(p.next.Type) {
+			p.unexpected(p.next, "loop variable")
+			return nil
+		}
+		p.advance()
+		v := p.parsePattern()
+		if v == nil {
+			return nil
+		}
+		stmt.Vars = append(stmt.Vars, v)
+		if !p.nextTokenIs(lexer.COMMA) || len(stmt.Vars) == 2 {
+			break
+		}
+		p.advance()
+	}
+
+	if !p.expectNext(lexer.IN, "'in'") {
+		return nil
+	}
+	p.advance()
+
+	stmt.Iterable = p.parseExpression(LOWEST)
+	if stmt.Iterable == nil {
+		return nil
+	}
+
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+// parseLabeledLoop parses a loop preceded by a label, such as `outer: loop {}`
+func (p *Parser) parseLabeledLoop() ast.Statement {
+	label := &ast.Identifier{Token: p.current, Value: p.current.Literal}
+	p.advance()
+	if !p.nextTokenIs(lexer.WHILE) && !p.nextTokenIs(lexer.FOR) && !p.nextTokenIs(lexer.LOOP) {
+		p.unexpected(p.next, "loop after label")
+		return nil
+	}
+	p.advance()
+
+	p.labels = append(p.labels, label.Value)
+	defer func() { p.labels = p.labels[:len(p.labels)-1] }()
+
+	switch p.current.Type {
+	case lexer.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			stmt.Label = label
+			return stmt
+		}
+	case lexer.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			stmt.Label = label
+			return stmt
+		}
+	default:
+		if stmt := p.parseLoopStatement(); stmt != nil {
+			stmt.Label = label
+			return stmt
+		}
+	}
+	return nil
+}
+
+// parseLoopExpression parses a loop used as an expression,
+// which evaluates to the value it is broken out of with
+func (p *Parser) parseLoopExpression() ast.Expression {
+	switch p.current.Type {
+	case lexer.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+	default:
+		if stmt := p.parseLoopStatement(); stmt != nil {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// isLabel reports whether name is the label of a loop being parsed
+func (p *Parser) isLabel(name string) bool {
+	for _, label := range p.labels {
+		if label == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Parser) parseLoopStatement() *ast.LoopStatement {
+	stmt := &ast.LoopStatement{Token: p.current}
+
+	if !p.expectNext(lexer.LBRACE, "`{`") {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+	if stmt.Body == nil {
+		return nil
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseBreakStatement() *ast.BreakStatement {
 	stmt := &ast.BreakStatement{Token: p.current}
+
+	// an identifier is only a label if it names an enclosing loop,
+	// otherwise it is the value being broken with
+	if p.nextTokenIs(lexer.IDENT) && p.sameLine(stmt.Token) && p.isLabel(p.next.Literal) {
+		p.advance()
+		stmt.Label = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+	}
+
+	if _, ok := p.prefixParseFns[p.next.Type]; ok && p.sameLine(stmt.Token) {
+		p.advance()
+		stmt.Value = p.parseExpression(LOWEST)
+		if stmt.Value == nil {
+			return nil
+		}
+	}
+
+	if p.nextTokenIs(lexer.SEMICOL) {
+		p.advance()
+	}
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.current}
+
+	if p.nextTokenIs(lexer.IDENT) && p.sameLine(stmt.Token) {
+		p.advance()
+		if !p.isLabel(p.current.Literal) {
+			p.errors = append(p.errors, Err{
+				Msg: fmt.Sprintf("Unknown loop label %s", p.current.Literal),
+				Con: p.current.Pos,
+			})
+			return nil
+		}
+		stmt.Label = &ast.Identifier{Token: p.current, Value: p.current.Literal}
+	}
+
+	if p.nextTokenIs(lexer.SEMICOL) {
+		p.advance()
+	}
+	return stmt
+}
+
+// sameLine reports whether p.next is on the same line as tok. The label
+// and value of a break or continue must start on the same line as it, so
+// that one without a semicolon does not take the next statement as its own.
+func (p *Parser) sameLine(tok lexer.Token) bool {
+	return p.next.Pos.Line == tok.Pos.Line
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.current}
+
+	if !p.nextTokenIs(lexer.LPAREN) {
+		if !p.expectNext(lexer.IDENT, "module name") {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.current, Value: p.current.Literal})
+		if p.nextTokenIs(lexer.SEMICOL) {
+			p.advance()
+		}
+		return stmt
+	}
+
+	p.advance() // p.current is now lparen
+	for {
+		if !p.expectNext(lexer.IDENT, "module name") {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.current, Value: p.current.Literal})
+
+		if !p.nextTokenIs(lexer.COMMA) {
+			break
+		}
+		p.advance()
+		// allow a trailing comma
+		if p.nextTokenIs(lexer.RPAREN) {
+			break
+		}
+	}
+
+	if !p.expectNext(lexer.RPAREN, "`,` or `)`") {
+		return nil
+	}
+	stmt.Close = p.current
+
 	if p.nextTokenIs(lexer.SEMICOL) {
 		p.advance()
 	}
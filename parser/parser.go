@@ -7,16 +7,30 @@ import (
 	"github.com/cartoon-raccoon/lemur/lexer"
 )
 
-//Parser - represents the parser for Monkey
+// Parser - represents the parser for Monkey
 type Parser struct {
 	lexer   *lexer.Lexer
 	current lexer.Token
 	next    lexer.Token
+	// nextErr is the error the lexer returned along with next
+	nextErr error
 
 	prefixParseFns map[string]prefixParseFn
 	infixParseFns  map[string]infixParseFn
 
 	errors []error
+	// warnings are reported for code that parses, but is likely to be wrong
+	warnings []error
+	// recovered is the number of errors that had been
+	// recovered from when the parser last synchronized
+	recovered int
+	// blocks is the number of blocks currently being parsed
+	blocks int
+	// labels holds the labels of the loops currently being parsed
+	labels []string
+	// literals is set while parsing the pattern of a match arm,
+	// the only place where literal and range patterns can be used
+	literals bool
 }
 
 type (
@@ -28,9 +42,11 @@ const (
 	_ int = iota
 	// LOWEST - The lowest precedence an expression can take
 	LOWEST
+	// ASSIGN - = or a compound assignment such as +=
+	ASSIGN
 	// EQUALS - ==
 	EQUALS
-	// COMPARE - < or >
+	// COMPARE - < or >, or membership with in
 	COMPARE
 	// SUM - a + b
 	SUM
@@ -51,26 +67,35 @@ const (
 )
 
 var precedences = map[string]int{
-	lexer.EQ:     EQUALS,
-	lexer.NE:     EQUALS,
-	lexer.LT:     COMPARE,
-	lexer.GT:     COMPARE,
-	lexer.LE:     COMPARE,
-	lexer.GE:     COMPARE,
-	lexer.ADD:    SUM,
-	lexer.SUB:    SUM,
-	lexer.MUL:    PRODUCT,
-	lexer.DIV:    PRODUCT,
-	lexer.BWAND:  BITWISE,
-	lexer.BWOR:   BITWISE,
-	lexer.BWNOT:  BITWISE,
-	lexer.BSR:    BITWISE,
-	lexer.BSL:    BITWISE,
-	lexer.LOR:    LOGIC,
-	lexer.LAND:   LOGIC,
-	lexer.LSBRKT: INDEX,
-	lexer.DOT:    DOT,
-	lexer.LPAREN: CALL,
+	lexer.ASSIGN:    ASSIGN,
+	lexer.ADDASSIGN: ASSIGN,
+	lexer.SUBASSIGN: ASSIGN,
+	lexer.MULASSIGN: ASSIGN,
+	lexer.DIVASSIGN: ASSIGN,
+	lexer.BWOASSIGN: ASSIGN,
+	lexer.BWAASSIGN: ASSIGN,
+	lexer.BWNASSIGN: ASSIGN,
+	lexer.EQ:        EQUALS,
+	lexer.NE:        EQUALS,
+	lexer.LT:        COMPARE,
+	lexer.GT:        COMPARE,
+	lexer.LE:        COMPARE,
+	lexer.GE:        COMPARE,
+	lexer.IN:        COMPARE,
+	lexer.ADD:       SUM,
+	lexer.SUB:       SUM,
+	lexer.MUL:       PRODUCT,
+	lexer.DIV:       PRODUCT,
+	lexer.BWAND:     BITWISE,
+	lexer.BWOR:      BITWISE,
+	lexer.BWNOT:     BITWISE,
+	lexer.BSR:       BITWISE,
+	lexer.BSL:       BITWISE,
+	lexer.LOR:       LOGIC,
+	lexer.LAND:      LOGIC,
+	lexer.LSBRKT:    INDEX,
+	lexer.DOT:       DOT,
+	lexer.LPAREN:    CALL,
 }
 
 func getPrecedence(tt string) int {
@@ -80,19 +105,15 @@ func getPrecedence(tt string) int {
 	return LOWEST
 }
 
-//New - returns a new Parser
+// New - returns a new Parser
+//
+// Errors from the lexer are collected along with syntax errors,
+// and reported by CheckErrors once parsing is done.
 func New(l *lexer.Lexer) (*Parser, error) {
 	p := &Parser{lexer: l}
 
-	err1 := p.advance()
-	err2 := p.advance()
-
-	if err1 != nil {
-		return p, err1
-	}
-	if err2 != nil {
-		return p, err2
-	}
+	p.advance()
+	p.advance()
 
 	// Registering prefix parse functions
 	p.prefixParseFns = make(map[string]prefixParseFn)
@@ -100,6 +121,7 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerPrefixFn(lexer.INTLIT, p.parseIntLiteral)
 	p.registerPrefixFn(lexer.FLTLIT, p.parseFltLiteral)
 	p.registerPrefixFn(lexer.STRLIT, p.parseStrLiteral)
+	p.registerPrefixFn(lexer.STRHEAD, p.parseInterpStr)
 	p.registerPrefixFn(lexer.TRUE, p.parseBoolLiteral)
 	p.registerPrefixFn(lexer.FALSE, p.parseBoolLiteral)
 	p.registerPrefixFn(lexer.LSBRKT, p.parseArrayLiteral)
@@ -107,9 +129,13 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerPrefixFn(lexer.SUB, p.parsePrefixExpr)
 	p.registerPrefixFn(lexer.LPAREN, p.parseGroupedExpr)
 	p.registerPrefixFn(lexer.IF, p.parseIfExpression)
+	p.registerPrefixFn(lexer.MATCH, p.parseMatchExpr)
 	p.registerPrefixFn(lexer.FUNCTION, p.parseFnLiteral)
 	p.registerPrefixFn(lexer.LBRACE, p.parseMapLiteral)
 	p.registerPrefixFn(lexer.BWNOT, p.parsePrefixExpr)
+	p.registerPrefixFn(lexer.WHILE, p.parseLoopExpression)
+	p.registerPrefixFn(lexer.FOR, p.parseLoopExpression)
+	p.registerPrefixFn(lexer.LOOP, p.parseLoopExpression)
 
 	// Registering infix parse functions
 	p.infixParseFns = make(map[string]infixParseFn)
@@ -123,6 +149,7 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerInfixFn(lexer.GT, p.parseInfixExpr)
 	p.registerInfixFn(lexer.EQ, p.parseInfixExpr)
 	p.registerInfixFn(lexer.NE, p.parseInfixExpr)
+	p.registerInfixFn(lexer.IN, p.parseInfixExpr)
 	p.registerInfixFn(lexer.BSL, p.parseInfixExpr)
 	p.registerInfixFn(lexer.BSR, p.parseInfixExpr)
 	p.registerInfixFn(lexer.BWAND, p.parseInfixExpr)
@@ -133,37 +160,49 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	p.registerInfixFn(lexer.LSBRKT, p.parseIndexExpr)
 	p.registerInfixFn(lexer.LPAREN, p.parseFunctionCall)
 	p.registerInfixFn(lexer.DOT, p.parseDotExpression)
+	for tt := range compoundOps {
+		p.registerInfixFn(tt, p.parseAssignExpr)
+	}
 
 	return p, nil
 }
 
 // Parse - parses a stream of tokens
+//
+// When a statement fails to parse, the error is recorded and parsing
+// resumes at the start of the next statement, so the returned program
+// holds every statement that parsed successfully.
 func (p *Parser) Parse() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 	program.Functions = []*ast.FunctionDecl{}
+	program.Classes = []*ast.ClassDecl{}
+	program.Traits = []*ast.TraitDecl{}
+	program.Impls = []*ast.ImplDecl{}
 
 	for !p.curTokenIs(lexer.EOF) {
+		start, errs := p.current, len(p.errors)
 		node := p.parseNode()
-		if node == nil {
-			return nil
+		if p.failed(node, errs) {
+			p.synchronize(start)
+			continue
 		}
-		switch node.(type) {
+		switch node := node.(type) {
 		case ast.Statement:
-			program.Statements = append(program.Statements, node.(ast.Statement))
-		case ast.Declaration:
-			switch node.(ast.Declaration).(type) {
-			case *ast.FunctionDecl:
-				program.Functions = append(program.Functions, node.(ast.Declaration).(*ast.FunctionDecl))
-			default:
-				//todo: return err
-				//! this is a fatal error and should panic
-				return nil
-			}
+			program.Statements = append(program.Statements, node)
+		case *ast.FunctionDecl:
+			program.Functions = append(program.Functions, node)
+		case *ast.ClassDecl:
+			program.Classes = append(program.Classes, node)
+		case *ast.TraitDecl:
+			program.Traits = append(program.Traits, node)
+		case *ast.ImplDecl:
+			program.Impls = append(program.Impls, node)
 		default:
-			//todo: return err
-			//! this is a fatal error and should panic
-			return nil
+			p.errors = append(p.errors, Err{
+				Msg: fmt.Sprintf("Unexpected declaration %s", node.String()),
+				Con: node.Context(),
+			})
 		}
 
 		p.advance()
@@ -172,27 +211,158 @@ func (p *Parser) Parse() *ast.Program {
 	return program
 }
 
+// parseNode parses the statement or declaration starting at p.current.
+// It returns nil if it failed to parse.
 func (p *Parser) parseNode() ast.Node {
+	// the parse functions return typed pointers, which must not
+	// be returned as a non-nil ast.Node when they fail
 	switch p.current.Type {
 	case lexer.LET:
-		return p.parseLetStatement()
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case lexer.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			return stmt
+		}
 	case lexer.WHILE:
-		return p.parseWhileStatement()
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.FOR:
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.LOOP:
+		if stmt := p.parseLoopStatement(); stmt != nil {
+			return stmt
+		}
 	case lexer.BREAK:
-		return p.parseBreakStatement()
+		if stmt := p.parseBreakStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.CONTINUE:
+		if stmt := p.parseContinueStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.IMPORT:
+		if stmt := p.parseImportStatement(); stmt != nil {
+			return stmt
+		}
+	case lexer.IDENT:
+		if p.nextTokenIs(lexer.COLON) {
+			if stmt := p.parseLabeledLoop(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		if stmt := p.parseExprStatement(); stmt != nil {
+			return stmt
+		}
 	case lexer.FUNCTION:
 		if p.nextTokenIs(lexer.LPAREN) {
-			return p.parseExprStatement()
+			if stmt := p.parseExprStatement(); stmt != nil {
+				return stmt
+			}
+			return nil
+		}
+		if decl := p.parseFuncDecl(); decl != nil {
+			return decl
+		}
+	case lexer.CLASS:
+		if decl := p.parseClassDecl(); decl != nil {
+			return decl
+		}
+	case lexer.TRAIT:
+		if decl := p.parseTraitDecl(); decl != nil {
+			return decl
+		}
+	case lexer.IMPL:
+		if decl := p.parseImplDecl(); decl != nil {
+			return decl
 		}
-		return p.parseFuncDecl()
 	default:
-		return p.parseExprStatement()
+		if stmt := p.parseExprStatement(); stmt != nil {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// failed reports whether parsing a node went wrong in a way that has not
+// already been recovered from. errs is the number of errors there were
+// before the node was parsed.
+func (p *Parser) failed(node ast.Node, errs int) bool {
+	if errs < p.recovered {
+		errs = p.recovered
 	}
+	return node == nil || len(p.errors) > errs
+}
+
+// synchronize skips the rest of a statement that failed to parse, starting
+// from wherever the error left p.current. It stops after a semicolon, after
+// a closing brace that ends the statement along with any semicolon following
+// it, or before a keyword that starts a new statement. A closing brace that was
+// not opened within the statement is left for the enclosing block.
+//
+// start is the first token of the failed statement, and the parser is
+// always moved past it so that parsing makes progress.
+func (p *Parser) synchronize(start lexer.Token) {
+	defer func() { p.recovered = len(p.errors) }()
+
+	depth := 0
+	for !p.curTokenIs(lexer.EOF) {
+		moved := p.current.Pos.Offset > start.Pos.Offset
+		if moved && depth == 0 && p.startsStatement() {
+			return
+		}
+
+		switch p.current.Type {
+		case lexer.LBRACE:
+			depth++
+		case lexer.RBRACE:
+			if depth == 0 && moved && p.blocks > 0 {
+				return
+			}
+			if depth <= 1 {
+				p.advance()
+				if p.curTokenIs(lexer.SEMICOL) {
+					p.advance()
+				}
+				return
+			}
+			depth--
+		case lexer.SEMICOL:
+			if depth == 0 {
+				p.advance()
+				return
+			}
+		}
+		p.advance()
+	}
+}
+
+// startsStatement reports whether p.current is a keyword
+// that can only appear at the start of a statement
+func (p *Parser) startsStatement() bool {
+	switch p.current.Type {
+	case lexer.LET, lexer.RETURN, lexer.WHILE, lexer.FOR, lexer.LOOP, lexer.BREAK, lexer.CONTINUE, lexer.IMPORT, lexer.CLASS, lexer.TRAIT, lexer.IMPL:
+		return true
+	case lexer.FUNCTION:
+		return p.nextTokenIs(lexer.IDENT)
+	}
+	return false
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	switch p.current.Type {
+	case lexer.ILLEGAL:
+		// the lexer has already reported this token
+		return nil
+	case lexer.EOF:
+		p.unexpected(p.current, "expression")
+		return nil
+	}
 	prefix := p.prefixParseFns[p.current.Type]
 	if prefix == nil {
 		p.errors = append(p.errors, Err{
@@ -202,6 +372,9 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 	leftExp := prefix()
+	if leftExp == nil {
+		return nil
+	}
 
 	for !p.nextTokenIs(lexer.SEMICOL) && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.next.Type]
@@ -241,14 +414,45 @@ func (p *Parser) nextTokenIs(t string) bool {
 	return p.next.Type == t
 }
 
-func (p *Parser) advance() error {
-	p.current = p.next
-	next, err := p.lexer.NextToken()
-	if err != nil {
-		return err
+// expectNext advances onto the next token if it has type t, otherwise
+// it records an error saying that what was expected instead
+func (p *Parser) expectNext(t string, what string) bool {
+	if p.nextTokenIs(t) {
+		p.advance()
+		return true
 	}
-	p.next = next
-	return nil
+	p.unexpected(p.next, what)
+	return false
+}
+
+// unexpected records an error for a token that is not what was expected.
+// Reaching the end of input is reported as such, since more input
+// could make the program valid.
+func (p *Parser) unexpected(tok lexer.Token, what string) {
+	if tok.Type == lexer.EOF {
+		p.errors = append(p.errors, Err{
+			Msg: fmt.Sprintf("Unexpected end of input, expected %s", what),
+			Con: tok.Pos,
+			EOF: true,
+		})
+		return
+	}
+	p.errors = append(p.errors, Err{
+		Msg: fmt.Sprintf("Expected %s, got `%s`", what, tok.Literal),
+		Con: tok.Pos,
+	})
+}
+
+// advance moves the parser on by one token. Errors from the lexer are
+// recorded, and the ILLEGAL token it produced takes the erroneous token's place.
+func (p *Parser) advance() {
+	// a lexer error is only recorded once its token becomes current,
+	// so that it is attributed to the statement that contains it
+	if p.nextErr != nil {
+		p.errors = append(p.errors, p.nextErr)
+	}
+	p.current = p.next
+	p.next, p.nextErr = p.lexer.NextToken()
 }
 
 // CheckErrors returns all the errors found while parsing
@@ -260,10 +464,42 @@ func (p *Parser) checkErrors() []error {
 	return p.errors
 }
 
-//Err represents the error that can be thrown by the parser
+// Warnings returns the warnings found while parsing, such as
+// match expressions that may not handle every value
+func (p *Parser) Warnings() []error {
+	return p.warnings
+}
+
+func (p *Parser) warn(msg string, con lexer.Context) {
+	p.warnings = append(p.warnings, Err{Msg: msg, Con: con})
+}
+
+// Incomplete reports whether parsing stopped because the input ended in
+// the middle of a statement, string or comment, meaning that more input
+// could complete the program. Input with any other error cannot be
+// completed, so it is only incomplete if every error is at its end.
+func (p *Parser) Incomplete() bool {
+	for _, err := range p.errors {
+		eof := false
+		switch err := err.(type) {
+		case Err:
+			eof = err.EOF
+		case lexer.Err:
+			eof = err.EOF
+		}
+		if !eof {
+			return false
+		}
+	}
+	return len(p.errors) > 0
+}
+
+// Err represents the error that can be thrown by the parser
 type Err struct {
 	Msg string
 	Con lexer.Context
+	// EOF is set when the error is caused by the input ending early
+	EOF bool
 }
 
 func (e Err) Error() string {
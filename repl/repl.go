@@ -8,8 +8,10 @@ import (
 	"runtime"
 	"strings"
 
+	"github.com/cartoon-raccoon/lemur/ast"
 	"github.com/cartoon-raccoon/lemur/compiler"
 	"github.com/cartoon-raccoon/lemur/lexer"
+	"github.com/cartoon-raccoon/lemur/object"
 	"github.com/cartoon-raccoon/lemur/parser"
 	"github.com/cartoon-raccoon/lemur/vm"
 )
@@ -20,8 +22,6 @@ const PROMPT = "lemur >> "
 // CONT - When a construct is incomplete
 const CONT = "> "
 
-var nestingLevel = 0
-
 // Repl - the interative Monkey shell
 type Repl struct {
 	lexer lexer.Lexer
@@ -44,13 +44,17 @@ func (r *Repl) Run(username string, in io.Reader, out io.Writer) {
 
 	// env := object.NewEnv()
 	// e := eval.New()
-	c := compiler.New()
-	vm := vm.New()
+	// state that persists between lines, so that globals can be reused
+	symbols := compiler.NewSymbolTable()
+	constants := []object.Object{}
+	globals := make([]object.Object, vm.GlobalsSize)
 
 	for {
-		prompt := PROMPT
+		fmt.Printf(PROMPT)
+
 		var line string
-		fmt.Printf(prompt)
+		var p *parser.Parser
+		var prog *ast.Program
 
 		for {
 			scanned := scanner.Scan()
@@ -59,15 +63,17 @@ func (r *Repl) Run(username string, in io.Reader, out io.Writer) {
 			}
 
 			line += scanner.Text() + "\n"
+			if strings.HasPrefix(line, ":") {
+				break
+			}
 
-			if isComplete(line) {
-				nestingLevel = 0
+			// keep reading lines until the parser has a complete program
+			p, _ = parser.New(lexer.New(line))
+			prog = p.Parse()
+			if !p.Incomplete() {
 				break
-			} else {
-				nestingLevel = 0
-				prompt = CONT
-				fmt.Printf(prompt)
 			}
+			fmt.Printf(CONT)
 		}
 
 		if strings.HasPrefix(line, ":") {
@@ -79,66 +85,39 @@ func (r *Repl) Run(username string, in io.Reader, out io.Writer) {
 			continue
 		}
 
-		l := lexer.New(line)
-		// for tok, err := l.NextToken(); tok.Type != lexer.EOF; tok, err = l.NextToken() {
-		// 	if err != nil {
-		// 		fmt.Println(err)
-		// 		break
-		// 	}
-		// 	fmt.Printf("%+v\n", tok)
-		// }
-		p, err := parser.New(l)
-		if err != nil {
-			fmt.Fprintf(os.Stdout, "%s\n", err.Error())
-			continue
-		}
-		prog := p.Parse()
 		if p.CheckErrors() != nil {
 			for _, err := range p.CheckErrors() {
 				fmt.Fprintf(os.Stdout, "%s\n", err.Error())
 			}
 			continue
 		}
+		for _, warning := range p.Warnings() {
+			fmt.Fprintf(os.Stdout, "warning: %s\n", warning.Error())
+		}
 
 		// res := e.Evaluate(prog, env)
 
 		// res.Display()
 
-		err = c.Compile(prog)
+		c := compiler.NewWithState(symbols, constants)
+		err := c.Compile(prog)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 			continue
 		}
+		bytecode := c.Bytecode()
+		constants = bytecode.Constants
 
-		err = vm.Run(c.Bytecode())
+		machine := vm.NewWithGlobals(globals)
+		err = machine.Run(bytecode)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 			continue
 		}
 
-		vm.LastPopped().Display()
-
-	}
-}
-
-// todo: add a stack to track whether the matching bracket is correct
-func isComplete(input string) bool {
-	for _, char := range input {
-		switch char {
-		case '[':
-			nestingLevel++
-		case '{':
-			nestingLevel++
-		case ']':
-			nestingLevel--
-		case '}':
-			nestingLevel--
+		if last := machine.LastPopped(); last != nil {
+			last.Display()
 		}
-	}
 
-	if nestingLevel != 0 {
-		return false
 	}
-
-	return true
 }
@@ -13,6 +13,9 @@ import (
 // StackSize is the maximum size the stack can take
 const StackSize = 2048
 
+// GlobalsSize is the maximum number of global variables
+const GlobalsSize = 65536
+
 // True - an invariant true object
 var True = &object.Boolean{Value: true}
 
@@ -27,17 +30,28 @@ type VM struct {
 	stack []object.Object
 	sp    int // stack pointer. Top of stack is stack[sp - 1]
 
+	globals []object.Object
+
 	ip int //instruction pointer
 }
 
 // New returns a new VM
 func New() *VM {
 	return &VM{
-		stack: make([]object.Object, StackSize),
-		sp:    0,
+		stack:   make([]object.Object, StackSize),
+		sp:      0,
+		globals: make([]object.Object, GlobalsSize),
 	}
 }
 
+// NewWithGlobals returns a new VM that shares the given globals,
+// so that they persist between programs
+func NewWithGlobals(globals []object.Object) *VM {
+	vm := New()
+	vm.globals = globals
+	return vm
+}
+
 // LastPopped returns the item just popped from the stack
 func (vm *VM) LastPopped() object.Object {
 	return vm.stack[vm.sp]
@@ -216,6 +230,271 @@ func (vm *VM) Run(bc *compiler.Bytecode) error {
 				return err
 			}
 			vm.push(vm.evalPrefixMinus(op))
+		case code.OpConcat:
+			count := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp < count {
+				return fmt.Errorf("stack underflow")
+			}
+			parts := make([]object.Object, count)
+			copy(parts, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+
+			err := vm.push(eval.Concat(parts))
+			if err != nil {
+				return err
+			}
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(vm.instructions[vm.ip+1:])
+			vm.ip += 2
+
+			err := vm.push(vm.globals[globalIndex])
+			if err != nil {
+				return err
+			}
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(vm.instructions[vm.ip+1:])
+			vm.ip += 2
+
+			val, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			vm.globals[globalIndex] = val
+		case code.OpArray:
+			count := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp < count {
+				return fmt.Errorf("stack underflow")
+			}
+			elements := make([]object.Object, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+
+			err := vm.push(&object.Array{Elements: elements})
+			if err != nil {
+				return err
+			}
+		case code.OpMap:
+			count := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp < count {
+				return fmt.Errorf("stack underflow")
+			}
+			hash := vm.buildMap(vm.stack[vm.sp-count : vm.sp])
+			vm.sp -= count
+
+			err := vm.push(hash)
+			if err != nil {
+				return err
+			}
+		case code.OpSet:
+			count := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp < count {
+				return fmt.Errorf("stack underflow")
+			}
+			set := vm.buildSet(vm.stack[vm.sp-count : vm.sp])
+			vm.sp -= count
+
+			err := vm.push(set)
+			if err != nil {
+				return err
+			}
+		case code.OpIn:
+			container, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			elem, err := vm.pop()
+			if err != nil {
+				return err
+			}
+
+			result := eval.Contains(container, elem, lexer.Context{})
+			if exc, ok := result.(*object.Exception); ok {
+				return fmt.Errorf("%s", exc.Msg)
+			}
+			err = vm.push(result)
+			if err != nil {
+				return err
+			}
+		case code.OpIndex:
+			index, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			left, err := vm.pop()
+			if err != nil {
+				return err
+			}
+
+			result := eval.Index(left, index, lexer.Context{})
+			if exc, ok := result.(*object.Exception); ok {
+				return fmt.Errorf("%s", exc.Msg)
+			}
+			err = vm.push(result)
+			if err != nil {
+				return err
+			}
+		case code.OpSetIndex:
+			val, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			index, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			left, err := vm.pop()
+			if err != nil {
+				return err
+			}
+
+			result := eval.SetIndex(left, index, val, lexer.Context{})
+			if exc, ok := result.(*object.Exception); ok {
+				return fmt.Errorf("%s", exc.Msg)
+			}
+			err = vm.push(result)
+			if err != nil {
+				return err
+			}
+		case code.OpDup:
+			count := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp < count {
+				return fmt.Errorf("stack underflow")
+			}
+			for _, obj := range vm.stack[vm.sp-count : vm.sp] {
+				err := vm.push(obj)
+				if err != nil {
+					return err
+				}
+			}
+		case code.OpNull:
+			err := vm.push(eval.NULL)
+			if err != nil {
+				return err
+			}
+		case code.OpJump:
+			pos := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip = pos - 1
+		case code.OpJumpNotTruthy:
+			pos := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			condition, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			if !eval.EvaluateTruthiness(condition) {
+				vm.ip = pos - 1
+			}
+		case code.OpIter:
+			vars := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			iterable, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			iter := eval.Iterate(iterable, vars, lexer.Context{})
+			if exc, ok := iter.(*object.Exception); ok {
+				return fmt.Errorf("%s", exc.Msg)
+			}
+			err = vm.push(iter)
+			if err != nil {
+				return err
+			}
+		case code.OpIterNext:
+			pos := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			vm.ip += 2
+
+			if vm.sp == 0 {
+				return fmt.Errorf("stack underflow")
+			}
+			iter, ok := vm.stack[vm.sp-1].(*object.Iterator)
+			if !ok {
+				return fmt.Errorf("cannot iterate over %T", vm.stack[vm.sp-1])
+			}
+			key, val, ok := iter.Next()
+			if !ok {
+				vm.ip = pos - 1
+				continue
+			}
+			err := vm.push(key)
+			if err != nil {
+				return err
+			}
+			err = vm.push(val)
+			if err != nil {
+				return err
+			}
+		case code.OpSwap:
+			if vm.sp < 2 {
+				return fmt.Errorf("stack underflow")
+			}
+			vm.stack[vm.sp-1], vm.stack[vm.sp-2] = vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+		case code.OpRange, code.OpSlice:
+			inclusive := code.ReadUint16(vm.instructions[vm.ip+1:]) == 1
+			vm.ip += 2
+
+			if vm.sp < 3 {
+				return fmt.Errorf("stack underflow")
+			}
+			start, end, step := vm.stack[vm.sp-3], vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+			vm.sp -= 3
+
+			var result object.Object
+			if op == code.OpRange {
+				result = eval.MakeRange(start, end, step, inclusive, lexer.Context{})
+			} else {
+				left, err := vm.pop()
+				if err != nil {
+					return err
+				}
+				result = eval.Slice(left, start, end, step, inclusive, lexer.Context{})
+			}
+			err := vm.push(result)
+			if err != nil {
+				return err
+			}
+		case code.OpMatchArray:
+			length := int(code.ReadUint16(vm.instructions[vm.ip+1:]))
+			atLeast := code.ReadUint16(vm.instructions[vm.ip+3:]) == 1
+			vm.ip += 4
+
+			val, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			arr, ok := val.(*object.Array)
+			matched := ok && (len(arr.Elements) == length || atLeast && len(arr.Elements) > length)
+			err = vm.push(nativeBool(matched))
+			if err != nil {
+				return err
+			}
+		case code.OpMatchKey:
+			key := vm.constants[code.ReadUint16(vm.instructions[vm.ip+1:])].(object.Hashable)
+			vm.ip += 2
+
+			val, err := vm.pop()
+			if err != nil {
+				return err
+			}
+			matched := false
+			if hash, ok := val.(*object.Map); ok {
+				_, matched = hash.Get(key)
+			}
+			err = vm.push(nativeBool(matched))
+			if err != nil {
+				return err
+			}
 		case code.OpBWNOT:
 			op, err := vm.pop()
 			if err != nil {
@@ -235,6 +514,49 @@ func (vm *VM) Run(bc *compiler.Bytecode) error {
 	return nil
 }
 
+// buildMap creates a map from alternating keys and values
+func (vm *VM) buildMap(elements []object.Object) object.Object {
+	hash := object.NewMap()
+
+	for i := 0; i+1 < len(elements); i += 2 {
+		key, ok := elements[i].(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as key for Map", elements[i]),
+				Con: lexer.Context{},
+			}
+		}
+		hash.Set(key, elements[i+1])
+	}
+
+	return hash
+}
+
+// buildSet creates a set from its elements
+func (vm *VM) buildSet(elements []object.Object) object.Object {
+	set := object.NewSet()
+
+	for _, elem := range elements {
+		hashable, ok := elem.(object.Hashable)
+		if !ok {
+			return &object.Exception{
+				Msg: fmt.Sprintf("Cannot use type %T as element of Set", elem),
+				Con: lexer.Context{},
+			}
+		}
+		set.Add(hashable)
+	}
+
+	return set
+}
+
+func nativeBool(b bool) object.Object {
+	if b {
+		return eval.TRUE
+	}
+	return eval.FALSE
+}
+
 func (vm *VM) push(o object.Object) error {
 	if vm.sp >= StackSize {
 		return fmt.Errorf("stack overflow")
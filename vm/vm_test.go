@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/cartoon-raccoon/lemur/compiler"
+	"github.com/cartoon-raccoon/lemur/eval"
 	"github.com/cartoon-raccoon/lemur/lexer"
 	"github.com/cartoon-raccoon/lemur/object"
 	"github.com/cartoon-raccoon/lemur/parser"
@@ -39,6 +40,159 @@ func TestIntegerArithmetic(t *testing.T) {
 	runVMTests(t, tests)
 }
 
+func TestStringExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`"lemur"`, "lemur"},
+		{`"lem" + "ur"`, "lemur"},
+		{`"1 + 2 = ${1 + 2}"`, "1 + 2 = 3"},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestGlobalAssignments(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = one + one; one + two", 3},
+		{"let x = 1; x = 5; x", 5},
+		{"let x = 1; x += 5; x *= 2; x", 12},
+		{"let a = 1; let b = 2; a = b = 3; a + b", 6},
+		{"let arr = [1, 2, 3]; arr[1] = 7; arr[1]", 7},
+		{"let arr = [1, 2, 3]; arr[2] -= 1; arr[2] + arr[0]", 3},
+		{`let m = {"k": 1}; m["k"] += 4; m["j"] = 2; m["k"] * m["j"]`, 10},
+		{`[10, 20][1] + {1: 5}[1]`, 25},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (false) { 10 }", nil},
+		{"if (1 > 2) { 10 } else { 20 }", 20},
+		{"if (false) { 1 } else if (true) { 2 } else { 3 }", 2},
+		{"if (true) { let x = 1; }", nil},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; let s = 0; while (i < 5) { i += 1; if (i == 2) { continue; }; s += i; } s", 13},
+		{"let i = 0; loop { i += 1; if (i == 10) { break; } } i", 10},
+		{"let s = 0; for x in [1, 2, 3] { s += x; } s", 6},
+		{`let s = ""; for k, v in {"a": 1, "b": 2} { s = s + k + "${v}"; } s`, "a1b2"},
+		{`let s = ""; for k in {"a": 1, "b": 2} { s = s + k; } s`, "ab"},
+		{`let s = ""; for c in "abc" { s = c + s; } s`, "cba"},
+		{"let s = 0; for i, x in [5, 6, 7] { if (i == 1) { continue; }; s += x; } s", 12},
+		{"let n = 0; for a in [1, 2, 3] { for b in [1, 2, 3] { if (b == 2) { break; }; n += 1; } } n", 3},
+		{"let x = 7; for x in [1, 2] { x; } x", 7},
+		{"let i = 0; let x = loop { i += 1; if (i == 3) { break i * 10; } }; x", 30},
+		{"let x = while (false) { 1; }; x", nil},
+		{`let x = for c in "abc" { if (c == "b") { break c; } }; x`, "b"},
+		{"let n = 0; outer: for a in [0, 1, 2] { for b in [0, 1, 2] { if (b == 1) { continue outer; }; if (a == 2) { break outer; }; n += 1; } } n", 2},
+		{"outer: loop { for x in [1, 2, 3] { for y in [4] { if (x == 2) { break outer x * 100; } } } }", 200},
+		{"loop { break 4; }", 4},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestRangesAndSlices(t *testing.T) {
+	tests := []vmTestCase{
+		{"let s = 0; for i in [1..=5] { s += i; } s", 15},
+		{"let s = 0; for i in [10..0:-2] { s += i; } s", 30},
+		{"let xs = [1, 2, 3, 4, 5]; let s = 0; for x in xs[1..4] { s += x; } s", 9},
+		{"[1, 2, 3, 4, 5][..:2][2]", 5},
+		{`"héllo"[1..=2]`, "él"},
+		{`"lemurs"[..5]`, "lemur"},
+		{"[1, 2, 3][-1]", 3},
+		{`"héllo"[-4]`, "é"},
+		{"let a = [1, 2, 3]; a[-3] = 8; a[0]", 8},
+		{"[1, 2, 3, 4, 5][-2..][0]", 4},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestSets(t *testing.T) {
+	tests := []vmTestCase{
+		{"2 in {1, 2}", true},
+		{"5 in {1, 2}", false},
+		{`"b" in {"a": 1, "b": 2}`, true},
+		{`"mur" in "lemur"`, true},
+		{"let s = 0; for x in {1, 2, 2, 3} { s += x; } s", 6},
+		{"let s = 0; for x in {1, 2} | {2, 3} { s += x; } s", 6},
+		{"let s = 0; for x in {1, 2, 3} & {2, 3, 4} { s += x; } s", 5},
+		{"let s = 0; for x in {1, 2, 3} - {2} { s += x; } s", 4},
+		{"let a = {1}; a |= {2}; 2 in a", true},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []vmTestCase{
+		{`match 2 { 1 => "one", 2 => "two", _ => "many" }`, "two"},
+		{`match 7 { 1 => "one", 2 => "two", _ => "many" }`, "many"},
+		{`match 1.0 { 1 => "int", _ => "other" }`, "other"},
+		{`match "a" { "a" => 1, _ => 2 }`, 1},
+		{`match true { true => 1, false => 0 }`, 1},
+		{`match -3 { -3 => 1, _ => 0 }`, 1},
+		{"match 5 { 0..5 => 1, 5..=9 => 2, _ => 3 }", 2},
+		{"match 10 { 0..10 => 1, _ => 2 }", 2},
+		{`match "x" { 0..10 => 1, _ => 2 }`, 2},
+		{"match [1, 2, 3] { [a, b] => a, [a, b, c] => c, _ => 0 }", 3},
+		{"match [1, 2, 3, 4] { [first, ...rest, last] => first + rest[1] + last, _ => 0 }", 8},
+		{"match [1, 2] { [a, ...rest, b] => { let n = 0; for x in rest { n += 1; } n } }", 0},
+		{"match [1, 2, 3] { [...rest, b] => rest[-1] + b }", 5},
+		{"match [1] { [first, ...rest, last] => 1, _ => 0 }", 0},
+		{"match [[1, 2], 3] { [[a, b], c] => a + b + c, _ => 0 }", 6},
+		{`match {"x": 1, "y": 2} { {x, y} => x + y, _ => 0 }`, 3},
+		{`match {"x": 1} { {x, y} => x + y, {x: 1} => 10, _ => 0 }`, 10},
+		{`match [1] { {x} => x, _ => 0 }`, 0},
+		{"match 4 { n if n > 3 => n * 2, n => n }", 8},
+		{"match 2 { n if n > 3 => n * 2, n => n }", 2},
+		{"match 9 { 1 => 1 }", nil},
+		{"let n = 1; match 5 { n => n }; n", 1},
+		{"match 1 { x => match 2 { y => x + y } }", 3},
+		{"match 3 { x => { let y = x * 2; y + 1 } }", 7},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"let arr = [1, 2, 3]; let y = arr[-4];", "Cannot get index -4 of array of length 3"},
+		{"let arr = [1, 2, 3]; arr[3] = 4;", "Cannot set index 3 of array of length 3"},
+		{"let s = {1}; [1] in s", "Cannot use type *object.Array as element of Set"},
+	}
+
+	for _, tt := range tests {
+		p, _ := parser.New(lexer.New(tt.input))
+		prog := p.Parse()
+
+		comp := compiler.New()
+		err := comp.Compile(prog)
+		if err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err = New().Run(comp.Bytecode())
+		if err == nil {
+			t.Errorf("Expected error running %q", tt.input)
+		} else if err.Error() != tt.err {
+			t.Errorf("Expected error %q, got %q", tt.err, err.Error())
+		}
+	}
+}
+
 func runVMTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -75,10 +229,32 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 	t.Helper()
 
 	switch expected := expected.(type) {
+	case nil:
+		if actual != eval.NULL {
+			t.Errorf("Expected null, got %s", actual.Inspect())
+		}
+	case bool:
+		result, ok := actual.(*object.Boolean)
+		if !ok {
+			t.Errorf("Expected boolean, got %T", actual)
+			return
+		}
+		if result.Value != expected {
+			t.Errorf("Values do not equate: got %t, expected %t", result.Value, expected)
+		}
 	case int:
 		err := testIntegerObject(int64(expected), actual)
 		if err != nil {
 			t.Errorf("testintobj: %s", err)
 		}
+	case string:
+		result, ok := actual.(*object.String)
+		if !ok {
+			t.Errorf("Expected string, got %T", actual)
+			return
+		}
+		if result.Value != expected {
+			t.Errorf("Values do not equate: got %q, expected %q", result.Value, expected)
+		}
 	}
 }